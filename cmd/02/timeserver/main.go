@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	_ "time/tzdata"
+
+	"github.com/go-chi/chi"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/02/timeserver"
+)
+
+const (
+	addr      = "localhost:8080"
+	cacheSize = 1024
+)
+
+func main() {
+	srv := timeserver.NewServer(cacheSize)
+
+	r := chi.NewMux()
+	r.Get("/time.png", srv.HandleTime)
+
+	if err := http.ListenAndServe(addr, r); err != nil {
+		log.Fatalf("HTTP server error: %v", err)
+	}
+}
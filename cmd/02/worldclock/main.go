@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/02/timepng"
+)
+
+func main() {
+	file, err := os.Create("worldclock.png")
+	if err != nil {
+		log.Fatalf("Failed to create file: %v", err)
+	}
+	defer file.Close()
+
+	clocks := []timepng.Clock{
+		{Label: "Moscow", Zone: "Europe/Moscow"},
+		{Label: "London", Zone: "Europe/London"},
+		{Label: "New York", Zone: "America/New_York"},
+	}
+	if err := timepng.WorldClockPNG(file, time.Now(), clocks, timepng.DefaultWorldClockOptions()); err != nil {
+		log.Fatalf("Failed to render clocks: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/authserver"
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+const addr = "localhost:8080"
+
+// Пользователи для демонстрации
+var users = map[string]string{
+	"alice": "alice-password",
+	"bob":   "bob-password",
+}
+
+func main() {
+	secret := os.Getenv("AUTH_SECRET")
+	if secret == "" {
+		log.Fatal("AUTH_SECRET is not set")
+	}
+
+	srv := authserver.NewServer(authserver.Config{
+		CheckPassword: func(username, password string) (string, bool) {
+			expected, ok := users[username]
+			return username, ok && expected == password
+		},
+		SignOptions: []jwt.Option{jwt.WithSignMethod(jwt.HS256), jwt.WithKey([]byte(secret))},
+		Issuer:      "http://" + addr,
+	}, authserver.NewMemoryStore())
+
+	r := chi.NewMux()
+	r.Post("/token/login", srv.HandleLogin)
+	r.Post("/token/refresh", srv.HandleRefresh)
+	r.Post("/token/revoke", srv.HandleRevoke)
+
+	if err := http.ListenAndServe(addr, r); err != nil {
+		log.Fatalf("HTTP server error: %v", err)
+	}
+}
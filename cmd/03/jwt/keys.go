@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+var encoding = base64.RawURLEncoding
+
+// parsedKey - ключ из файла или переменной окружения
+type parsedKey struct {
+	secret  []byte
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+func (k parsedKey) options() []jwt.Option {
+	switch {
+	case k.private != nil:
+		return []jwt.Option{jwt.WithPrivateKey(k.private)}
+	case k.public != nil:
+		return []jwt.Option{jwt.WithPublicKey(k.public)}
+	default:
+		return []jwt.Option{jwt.WithKey(k.secret)}
+	}
+}
+
+// parseKey разбирает ключ в формате PEM, JWK или секрет HMAC
+func parseKey(data []byte) (parsedKey, error) {
+	data = bytes.TrimSpace(data)
+	if block, _ := pem.Decode(data); block != nil {
+		k, err := jwt.ParsePEM(data, nil)
+		if err != nil {
+			return parsedKey{}, err
+		}
+		return fromKey(k.Key)
+	}
+	if len(data) != 0 && data[0] == '{' {
+		var k jwt.JWK
+		if err := json.Unmarshal(data, &k); err != nil {
+			return parsedKey{}, err
+		}
+		return fromKey(k.Key)
+	}
+	return parsedKey{secret: data}, nil
+}
+
+func fromKey(key interface{}) (parsedKey, error) {
+	switch key := key.(type) {
+	case []byte:
+		return parsedKey{secret: key}, nil
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return parsedKey{private: key.(crypto.Signer)}, nil
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return parsedKey{public: key}, nil
+	default:
+		return parsedKey{}, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+func runKeygen(args []string, e env) error {
+	fs := newFlagSet("keygen", e)
+	keyType := fs.String("type", "hmac", "key type: hmac, rsa, ec or ed25519")
+	bits := fs.Int("bits", 0, "key size: 256 for hmac, 2048 for rsa, 256 or 384 for ec by default")
+	format := fs.String("format", "pem", "output format: pem or jwk (hmac keys are printed as is with pem)")
+	kid := fs.String("kid", "", "key id for jwk")
+	alg := fs.String("alg", "", "sign method for jwk")
+	public := fs.String("public", "", "file to write the public key to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "pem" && *format != "jwk" {
+		return fmt.Errorf("%w: unknown format %q", errUsage, *format)
+	}
+
+	key, err := generateKey(*keyType, *bits)
+	if err != nil {
+		return err
+	}
+	out, err := formatKey(key, *format, *kid, jwt.SignMethod(*alg))
+	if err != nil {
+		return err
+	}
+	if _, err := e.stdout.Write(out); err != nil {
+		return err
+	}
+
+	if *public == "" {
+		return nil
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("%w: hmac keys have no public part", errUsage)
+	}
+	out, err = formatKey(signer.Public(), *format, *kid, jwt.SignMethod(*alg))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*public, out, 0o644)
+}
+
+func generateKey(keyType string, bits int) (interface{}, error) {
+	switch keyType {
+	case "hmac":
+		if bits == 0 {
+			bits = 256
+		}
+		if bits%8 != 0 {
+			return nil, fmt.Errorf("%w: hmac key size must be a multiple of 8", errUsage)
+		}
+		raw := make([]byte, bits/8)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		// Печатаемый секрет сам является ключом
+		return []byte(encoding.EncodeToString(raw)), nil
+	case "rsa":
+		if bits == 0 {
+			bits = 2048
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	case "ec":
+		curves := map[int]elliptic.Curve{0: elliptic.P256(), 256: elliptic.P256(), 384: elliptic.P384()}
+		curve, ok := curves[bits]
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported ec key size %d", errUsage, bits)
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	case "ed25519":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("%w: unknown key type %q", errUsage, keyType)
+	}
+}
+
+func formatKey(key interface{}, format, kid string, alg jwt.SignMethod) ([]byte, error) {
+	if format == "jwk" {
+		out, err := json.MarshalIndent(jwt.JWK{KeyID: kid, Algorithm: alg, Key: key}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	}
+
+	var block *pem.Block
+	switch key := key.(type) {
+	case []byte:
+		return append(key, '\n'), nil
+	case crypto.Signer:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	default:
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return nil, err
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	}
+	return pem.EncodeToMemory(block), nil
+}
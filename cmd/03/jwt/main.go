@@ -0,0 +1,321 @@
+// Команда jwt создает, проверяет и разбирает JWT токены и генерирует ключи.
+//
+//	jwt encode -alg HS256 -key-env JWT_SECRET -ttl 1h '{"user":42}'
+//	jwt decode -alg RS256 -key public.pem < token
+//	jwt inspect < token
+//	jwt keygen -type ec -bits 256 -format jwk -public public.json
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+// Коды завершения
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitMalformed
+	exitSignature
+	exitExpired
+	exitClaims
+)
+
+var errUsage = errors.New("usage error")
+
+const usage = `usage: jwt <command> [flags]
+
+commands:
+  encode   create a signed token from a JSON payload
+  decode   verify a token and print its header and claims
+  inspect  print the header and claims of a token without verification
+  keygen   generate a key
+`
+
+// env - окружение команды, подменяется в тестах
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr, getenv: os.Getenv}))
+}
+
+func run(args []string, e env) int {
+	if len(args) == 0 {
+		_, _ = io.WriteString(e.stderr, usage)
+		return exitUsage
+	}
+	commands := map[string]func([]string, env) error{
+		"encode":  runEncode,
+		"decode":  runDecode,
+		"inspect": runInspect,
+		"keygen":  runKeygen,
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		_, _ = io.WriteString(e.stderr, usage)
+		return exitUsage
+	}
+	if err := cmd(args[1:], e); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			_, _ = fmt.Fprintf(e.stderr, "jwt %s: %v\n", args[0], err)
+		}
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// exitCode различает просроченные, неверно подписанные и некорректные токены
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		return exitUsage
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return exitExpired
+	case errors.Is(err, jwt.ErrSignatureInvalid), errors.Is(err, jwt.ErrSignMethodMismatched):
+		return exitSignature
+	case errors.Is(err, jwt.ErrInvalidToken):
+		return exitMalformed
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued),
+		errors.Is(err, jwt.ErrInvalidIssuer), errors.Is(err, jwt.ErrInvalidSubject),
+		errors.Is(err, jwt.ErrInvalidAudience), errors.Is(err, jwt.ErrInvalidID):
+		return exitClaims
+	default:
+		return exitError
+	}
+}
+
+func newFlagSet(name string, e env) *flag.FlagSet {
+	fs := flag.NewFlagSet("jwt "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// keyFlags - флаги, задающие ключ
+type keyFlags struct {
+	alg    string
+	file   string
+	envVar string
+}
+
+func (k *keyFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&k.alg, "alg", string(jwt.HS256), "sign method")
+	fs.StringVar(&k.file, "key", "", "key file: HMAC secret, PEM or JWK")
+	fs.StringVar(&k.envVar, "key-env", "", "environment variable with the key")
+}
+
+func (k *keyFlags) options(e env) ([]jwt.Option, error) {
+	var data []byte
+	switch {
+	case k.file != "" && k.envVar != "":
+		return nil, fmt.Errorf("%w: -key and -key-env are mutually exclusive", errUsage)
+	case k.file != "":
+		var err error
+		if data, err = os.ReadFile(k.file); err != nil {
+			return nil, err
+		}
+	case k.envVar != "":
+		data = []byte(e.getenv(k.envVar))
+		if len(data) == 0 {
+			return nil, fmt.Errorf("%w: %s is empty", errUsage, k.envVar)
+		}
+	default:
+		return nil, fmt.Errorf("%w: -key or -key-env is required", errUsage)
+	}
+	key, err := parseKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]jwt.Option{jwt.WithSignMethod(jwt.SignMethod(k.alg))}, key.options()...), nil
+}
+
+// claimFlags - флаги зарегистрированных полей
+type claimFlags struct {
+	iss, sub, aud string
+}
+
+func (c *claimFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.iss, "iss", "", "issuer")
+	fs.StringVar(&c.sub, "sub", "", "subject")
+	fs.StringVar(&c.aud, "aud", "", "comma separated audience")
+}
+
+func (c *claimFlags) options() []jwt.Option {
+	var opts []jwt.Option
+	if c.iss != "" {
+		opts = append(opts, jwt.WithIssuer(c.iss))
+	}
+	if c.sub != "" {
+		opts = append(opts, jwt.WithSubject(c.sub))
+	}
+	if c.aud != "" {
+		opts = append(opts, jwt.WithAudience(strings.Split(c.aud, ",")...))
+	}
+	return opts
+}
+
+func runEncode(args []string, e env) error {
+	fs := newFlagSet("encode", e)
+	var key keyFlags
+	var claims claimFlags
+	key.register(fs)
+	claims.register(fs)
+	ttl := fs.Duration("ttl", 0, "token lifetime")
+	exp := fs.String("exp", "", "expiration time, RFC 3339 or unix seconds")
+	kid := fs.String("kid", "", "key id")
+	jti := fs.String("jti", "", "token id")
+	iat := fs.Bool("iat", true, "add issued at claim")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts, err := key.options(e)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, claims.options()...)
+	if *ttl != 0 {
+		opts = append(opts, jwt.WithTTL(*ttl))
+	}
+	if *exp != "" {
+		t, err := parseTime(*exp)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, jwt.WithExpires(t))
+	}
+	if *kid != "" {
+		opts = append(opts, jwt.WithKeyID(*kid))
+	}
+	if *jti != "" {
+		opts = append(opts, jwt.WithID(*jti))
+	}
+	if *iat {
+		opts = append(opts, jwt.WithIssuedAt())
+	}
+
+	payload, err := readInput(fs.Args(), e)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(payload) {
+		return fmt.Errorf("%w: payload is not valid JSON", errUsage)
+	}
+	token, err := jwt.Encode(json.RawMessage(payload), opts...)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.stdout, "%s\n", token)
+	return err
+}
+
+// parsedToken - разобранный токен для вывода
+type parsedToken struct {
+	Header  jwt.Header      `json:"header"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func runDecode(args []string, e env) error {
+	fs := newFlagSet("decode", e)
+	var key keyFlags
+	var claims claimFlags
+	key.register(fs)
+	claims.register(fs)
+	leeway := fs.Duration("leeway", 0, "allowed clock skew")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *leeway < 0 {
+		return fmt.Errorf("%w: -leeway must not be negative", errUsage)
+	}
+
+	opts, err := key.options(e)
+	if err != nil {
+		return err
+	}
+	token, err := readInput(fs.Args(), e)
+	if err != nil {
+		return err
+	}
+
+	var res parsedToken
+	var data json.RawMessage
+	var registered jwt.Claims
+	opts = append(opts, claims.options()...)
+	opts = append(opts, jwt.WithLeeway(*leeway), jwt.WithHeader(&res.Header), jwt.WithClaims(&registered))
+	if err := jwt.Decode(token, &data, opts...); err != nil {
+		return err
+	}
+	if res.Payload, err = json.Marshal(jwt.RawClaims{Data: data, Claims: registered}); err != nil {
+		return err
+	}
+	return printJSON(e.stdout, res)
+}
+
+func runInspect(args []string, e env) error {
+	fs := newFlagSet("inspect", e)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	token, err := readInput(fs.Args(), e)
+	if err != nil {
+		return err
+	}
+
+	h, claims, err := jwt.ParseUnverified(token)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return err
+	}
+	return printJSON(e.stdout, parsedToken{Header: h, Payload: payload})
+}
+
+// readInput читает значение из аргумента или, если его нет или он равен "-", из stdin
+func readInput(args []string, e env) ([]byte, error) {
+	switch {
+	case len(args) > 1:
+		return nil, fmt.Errorf("%w: too many arguments", errUsage)
+	case len(args) == 1 && args[0] != "-":
+		return []byte(args[0]), nil
+	}
+	data, err := io.ReadAll(e.stdin)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(data), nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid time %q", errUsage, s)
+	}
+	return t, nil
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
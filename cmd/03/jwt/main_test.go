@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runCmd(t *testing.T, stdin string, args ...string) (int, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, env{
+		stdin:  strings.NewReader(stdin),
+		stdout: &stdout,
+		stderr: &stderr,
+		getenv: func(name string) string {
+			if name == "JWT_SECRET" {
+				return "secret"
+			}
+			return ""
+		},
+	})
+	return code, stdout.String()
+}
+
+func TestEncodeDecode(t *testing.T) {
+	code, token := runCmd(t, `{"user":42}`, "encode", "-key-env", "JWT_SECRET", "-ttl", "1h", "-iss", "cli")
+	require.Equal(t, exitOK, code)
+
+	code, out := runCmd(t, token, "decode", "-key-env", "JWT_SECRET", "-iss", "cli")
+	require.Equal(t, exitOK, code)
+	require.Contains(t, out, `"user": 42`)
+	require.Contains(t, out, `"iss": "cli"`)
+
+	code, out = runCmd(t, "", "inspect", strings.TrimSpace(token))
+	require.Equal(t, exitOK, code)
+	require.Contains(t, out, `"alg": "HS256"`)
+}
+
+// expiredToken подписывает токен, истекший час назад: encode не выпускает такие токены
+func expiredToken() string {
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		enc.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, time.Now().Add(-time.Hour).Unix())))
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestExitCodes(t *testing.T) {
+	expired := expiredToken()
+	_, valid := runCmd(t, `{}`, "encode", "-key-env", "JWT_SECRET", "-iss", "cli")
+
+	for _, tc := range []struct {
+		Name  string
+		Stdin string
+		Args  []string
+		Code  int
+	}{
+		{Name: "no command", Code: exitUsage},
+		{Name: "unknown command", Args: []string{"sign"}, Code: exitUsage},
+		{Name: "no key", Stdin: valid, Args: []string{"decode"}, Code: exitUsage},
+		{Name: "bad payload", Stdin: "{", Args: []string{"encode", "-key-env", "JWT_SECRET"}, Code: exitUsage},
+		{Name: "malformed", Stdin: "abc", Args: []string{"decode", "-key-env", "JWT_SECRET"}, Code: exitMalformed},
+		{Name: "malformed inspect", Stdin: "a.b.c", Args: []string{"inspect"}, Code: exitMalformed},
+		{Name: "signature", Stdin: valid, Args: []string{"decode", "-key", writeFile(t, "other")}, Code: exitSignature},
+		{Name: "alg", Stdin: valid, Args: []string{"decode", "-alg", "HS512", "-key-env", "JWT_SECRET"}, Code: exitSignature},
+		{Name: "expired", Stdin: expired, Args: []string{"decode", "-key-env", "JWT_SECRET"}, Code: exitExpired},
+		{Name: "expired leeway", Stdin: expired, Args: []string{"decode", "-key-env", "JWT_SECRET", "-leeway", "1m"}, Code: exitExpired},
+		{Name: "negative leeway", Stdin: valid, Args: []string{"decode", "-key-env", "JWT_SECRET", "-leeway", "-2s"}, Code: exitUsage},
+		{Name: "claims", Stdin: valid, Args: []string{"decode", "-key-env", "JWT_SECRET", "-aud", "api"}, Code: exitClaims},
+	} {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			code, _ := runCmd(t, tc.Stdin, tc.Args...)
+			require.Equal(t, tc.Code, code)
+		})
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestKeygen(t *testing.T) {
+	for _, tc := range []struct {
+		Type   string
+		Alg    string
+		Format string
+	}{
+		{Type: "hmac", Alg: "HS256", Format: "pem"},
+		{Type: "hmac", Alg: "HS512", Format: "jwk"},
+		{Type: "rsa", Alg: "PS256", Format: "pem"},
+		{Type: "ec", Alg: "ES256", Format: "jwk"},
+		{Type: "ed25519", Alg: "EdDSA", Format: "pem"},
+	} {
+		tc := tc
+		t.Run(tc.Type+"/"+tc.Format, func(t *testing.T) {
+			dir := t.TempDir()
+			public := filepath.Join(dir, "public")
+			args := []string{"keygen", "-type", tc.Type, "-format", tc.Format}
+			if tc.Type != "hmac" {
+				args = append(args, "-public", public)
+			}
+			code, key := runCmd(t, "", args...)
+			require.Equal(t, exitOK, code)
+			private := writeFile(t, key)
+			if tc.Type == "hmac" {
+				public = private
+			}
+
+			code, token := runCmd(t, `"hello"`, "encode", "-alg", tc.Alg, "-key", private)
+			require.Equal(t, exitOK, code)
+			code, _ = runCmd(t, token, "decode", "-alg", tc.Alg, "-key", public)
+			require.Equal(t, exitOK, code)
+		})
+	}
+}
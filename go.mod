@@ -4,14 +4,16 @@ go 1.18
 
 require (
 	github.com/go-chi/chi v1.5.4
-	github.com/golang/mock v1.6.0
-	github.com/stretchr/testify v1.7.0
+	github.com/stretchr/testify v1.8.1
+	go.etcd.io/bbolt v1.3.7
 	go.uber.org/goleak v1.1.12
+	golang.org/x/crypto v0.5.0
 	golang.org/x/exp v0.0.0-20220428152302-39d4317da171
 )
 
 require (
-	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+	golang.org/x/sys v0.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,56 @@
+package timepng
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// TimeGIF записывает в `out` зацикленную анимацию в формате gif со временем `t`:
+// кадры с включенным и выключенным двоеточием сменяются каждые `delay`
+func TimeGIF(out io.Writer, t time.Time, layout string, opts Options, delay time.Duration) error {
+	anim := &gif.GIF{}
+	for _, hide := range []bool{false, true} {
+		opts.HideColon = hide
+		img, err := RenderTime(t, layout, opts)
+		if err != nil {
+			return err
+		}
+		anim.Image = append(anim.Image, toPaletted(img))
+		anim.Delay = append(anim.Delay, int(delay/(10*time.Millisecond)))
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+	return gif.EncodeAll(out, anim)
+}
+
+// toPaletted переводит изображение в палитру. Если в изображении не больше 256
+// цветов, они сохраняются точно, иначе используется палитра Plan9
+func toPaletted(img *image.RGBA) *image.Paletted {
+	var pal color.Palette
+	index := make(map[color.RGBA]uint8)
+	for i := 0; i < len(img.Pix); i += 4 {
+		c := color.RGBA{R: img.Pix[i], G: img.Pix[i+1], B: img.Pix[i+2], A: img.Pix[i+3]}
+		if _, ok := index[c]; ok {
+			continue
+		}
+		if len(pal) == 256 {
+			res := image.NewPaletted(img.Rect, palette.Plan9)
+			draw.Draw(res, img.Rect, img, img.Rect.Min, draw.Src)
+			return res
+		}
+		index[c] = uint8(len(pal))
+		pal = append(pal, c)
+	}
+
+	res := image.NewPaletted(img.Rect, pal)
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			res.SetColorIndex(x, y, index[img.RGBAAt(x, y)])
+		}
+	}
+	return res
+}
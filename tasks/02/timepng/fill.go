@@ -0,0 +1,65 @@
+package timepng
+
+import (
+	"image"
+	"image/color"
+	"runtime"
+	"sync"
+)
+
+// parallelPixels - минимальное число пикселей, начиная с которого
+// заполнение изображения распределяется по нескольким горутинам
+var parallelPixels = 1 << 20
+
+// colorSpan возвращает строку из `n` пикселей цвета `c` в формате image.RGBA
+func colorSpan(c color.Color, n int) []byte {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	span := make([]byte, 4*n)
+	if n == 0 {
+		return span
+	}
+	span[0], span[1], span[2], span[3] = rgba.R, rgba.G, rgba.B, rgba.A
+	for filled := 4; filled < len(span); filled *= 2 {
+		copy(span[filled:], span[:filled])
+	}
+	return span
+}
+
+// fillRect заполняет прямоугольник `r` изображения `img` строкой пикселей `span`,
+// длина которой должна совпадать с шириной `r`
+func fillRect(img *image.RGBA, r image.Rectangle, span []byte) {
+	r = r.Intersect(img.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		offset := img.PixOffset(r.Min.X, y)
+		copy(img.Pix[offset:offset+4*r.Dx()], span)
+	}
+}
+
+// parallelRows вызывает `fill` для диапазонов строк [from, to), покрывающих
+// [0, rows). Если изображение достаточно большое (rows*width пикселей),
+// диапазоны обрабатываются параллельно
+func parallelRows(rows, width int, fill func(from, to int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > rows {
+		workers = rows
+	}
+	if workers <= 1 || rows*width < parallelPixels {
+		fill(0, rows)
+		return
+	}
+
+	var wg sync.WaitGroup
+	chunk := (rows + workers - 1) / workers
+	for from := 0; from < rows; from += chunk {
+		to := from + chunk
+		if to > rows {
+			to = rows
+		}
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			fill(from, to)
+		}(from, to)
+	}
+	wg.Wait()
+}
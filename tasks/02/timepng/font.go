@@ -0,0 +1,222 @@
+package timepng
+
+// letters - маски букв и знаков для подписей. Строчные буквы рисуются как заглавные
+var letters = map[rune][]int{
+	'A': {
+		0, 1, 0,
+		1, 0, 1,
+		1, 1, 1,
+		1, 0, 1,
+		1, 0, 1,
+	},
+	'B': {
+		1, 1, 0,
+		1, 0, 1,
+		1, 1, 0,
+		1, 0, 1,
+		1, 1, 0,
+	},
+	'C': {
+		0, 1, 1,
+		1, 0, 0,
+		1, 0, 0,
+		1, 0, 0,
+		0, 1, 1,
+	},
+	'D': {
+		1, 1, 0,
+		1, 0, 1,
+		1, 0, 1,
+		1, 0, 1,
+		1, 1, 0,
+	},
+	'E': {
+		1, 1, 1,
+		1, 0, 0,
+		1, 1, 0,
+		1, 0, 0,
+		1, 1, 1,
+	},
+	'F': {
+		1, 1, 1,
+		1, 0, 0,
+		1, 1, 0,
+		1, 0, 0,
+		1, 0, 0,
+	},
+	'G': {
+		0, 1, 1,
+		1, 0, 0,
+		1, 0, 1,
+		1, 0, 1,
+		0, 1, 1,
+	},
+	'H': {
+		1, 0, 1,
+		1, 0, 1,
+		1, 1, 1,
+		1, 0, 1,
+		1, 0, 1,
+	},
+	'I': {
+		1, 1, 1,
+		0, 1, 0,
+		0, 1, 0,
+		0, 1, 0,
+		1, 1, 1,
+	},
+	'J': {
+		0, 0, 1,
+		0, 0, 1,
+		0, 0, 1,
+		1, 0, 1,
+		0, 1, 0,
+	},
+	'K': {
+		1, 0, 1,
+		1, 0, 1,
+		1, 1, 0,
+		1, 0, 1,
+		1, 0, 1,
+	},
+	'L': {
+		1, 0, 0,
+		1, 0, 0,
+		1, 0, 0,
+		1, 0, 0,
+		1, 1, 1,
+	},
+	'M': {
+		1, 0, 1,
+		1, 1, 1,
+		1, 1, 1,
+		1, 0, 1,
+		1, 0, 1,
+	},
+	'N': {
+		1, 1, 0,
+		1, 0, 1,
+		1, 0, 1,
+		1, 0, 1,
+		1, 0, 1,
+	},
+	'O': {
+		0, 1, 0,
+		1, 0, 1,
+		1, 0, 1,
+		1, 0, 1,
+		0, 1, 0,
+	},
+	'P': {
+		1, 1, 0,
+		1, 0, 1,
+		1, 1, 0,
+		1, 0, 0,
+		1, 0, 0,
+	},
+	'Q': {
+		0, 1, 0,
+		1, 0, 1,
+		1, 0, 1,
+		1, 1, 0,
+		0, 1, 1,
+	},
+	'R': {
+		1, 1, 0,
+		1, 0, 1,
+		1, 1, 0,
+		1, 0, 1,
+		1, 0, 1,
+	},
+	'S': {
+		0, 1, 1,
+		1, 0, 0,
+		0, 1, 0,
+		0, 0, 1,
+		1, 1, 0,
+	},
+	'T': {
+		1, 1, 1,
+		0, 1, 0,
+		0, 1, 0,
+		0, 1, 0,
+		0, 1, 0,
+	},
+	'U': {
+		1, 0, 1,
+		1, 0, 1,
+		1, 0, 1,
+		1, 0, 1,
+		1, 1, 1,
+	},
+	'V': {
+		1, 0, 1,
+		1, 0, 1,
+		1, 0, 1,
+		1, 0, 1,
+		0, 1, 0,
+	},
+	'W': {
+		1, 0, 1,
+		1, 0, 1,
+		1, 1, 1,
+		1, 1, 1,
+		1, 0, 1,
+	},
+	'X': {
+		1, 0, 1,
+		1, 0, 1,
+		0, 1, 0,
+		1, 0, 1,
+		1, 0, 1,
+	},
+	'Y': {
+		1, 0, 1,
+		1, 0, 1,
+		0, 1, 0,
+		0, 1, 0,
+		0, 1, 0,
+	},
+	'Z': {
+		1, 1, 1,
+		0, 0, 1,
+		0, 1, 0,
+		1, 0, 0,
+		1, 1, 1,
+	},
+	' ': {
+		0, 0, 0,
+		0, 0, 0,
+		0, 0, 0,
+		0, 0, 0,
+		0, 0, 0,
+	},
+	'-': {
+		0, 0, 0,
+		0, 0, 0,
+		1, 1, 1,
+		0, 0, 0,
+		0, 0, 0,
+	},
+	'.': {
+		0, 0, 0,
+		0, 0, 0,
+		0, 0, 0,
+		0, 0, 0,
+		0, 1, 0,
+	},
+	'/': {
+		0, 0, 1,
+		0, 0, 1,
+		0, 1, 0,
+		1, 0, 0,
+		1, 0, 0,
+	},
+	'_': {
+		0, 0, 0,
+		0, 0, 0,
+		0, 0, 0,
+		0, 0, 0,
+		1, 1, 1,
+	},
+}
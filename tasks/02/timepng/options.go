@@ -0,0 +1,88 @@
+package timepng
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+)
+
+var ErrInvalidOptions = errors.New("invalid options")
+
+// Shape задает форму, которой рисуется одна клетка маски
+type Shape int
+
+const (
+	// ShapeSquare - клетка закрашивается целиком
+	ShapeSquare Shape = iota
+	// ShapeCircle - клетка рисуется сглаженным кругом (как на точечной матрице)
+	ShapeCircle
+	// ShapeRoundedSquare - клетка рисуется сглаженным квадратом со скругленными углами
+	ShapeRoundedSquare
+)
+
+// Options задает оформление картинки. Нулевые значения цветов означают
+// прозрачность, поэтому удобнее начинать с DefaultOptions
+type Options struct {
+	// Color - цвет закрашенных клеток
+	Color color.Color
+	// Colors - цвета символов по порядку (циклически), имеют приоритет над Color
+	Colors []color.Color
+	// GradientTo - если задан, символы закрашиваются горизонтальным
+	// градиентом от Color до GradientTo
+	GradientTo color.Color
+	// Background - цвет фона, nil означает прозрачный фон
+	Background color.Color
+	// Scale - размер клетки маски в пикселях
+	Scale int
+	// Padding - отступ от края картинки (или рамки) до символов в пикселях
+	Padding int
+	// Gap - промежуток между символами в пикселях
+	Gap int
+	// Border - толщина рамки в пикселях, 0 - без рамки
+	Border int
+	// BorderColor - цвет рамки, по умолчанию совпадает с Color
+	BorderColor color.Color
+	// Shape - форма клетки для StyleMask
+	Shape Shape
+	// Style - способ отрисовки символов
+	Style Style
+	// Segments - параметры сегментных стилей
+	Segments SegmentOptions
+	// HideColon - выключить двоеточие, чтобы в анимации оно мигало
+	HideColon bool
+}
+
+// DefaultOptions возвращает оформление, совпадающее с TimePNG
+func DefaultOptions(c color.Color, scale int) Options {
+	return Options{
+		Color: c,
+		Scale: scale,
+		Gap:   scale,
+	}
+}
+
+func (opts Options) validate() error {
+	if opts.Color == nil && len(opts.Colors) == 0 {
+		return fmt.Errorf("%w: color is not set", ErrInvalidOptions)
+	}
+	if opts.Scale < 1 || opts.Padding < 0 || opts.Gap < 0 || opts.Border < 0 {
+		return fmt.Errorf("%w: invalid size", ErrInvalidOptions)
+	}
+	if opts.Shape < ShapeSquare || opts.Shape > ShapeRoundedSquare {
+		return fmt.Errorf("%w: unknown shape %d", ErrInvalidOptions, opts.Shape)
+	}
+	if opts.Style < StyleMask || opts.Style > StyleSegments16 {
+		return fmt.Errorf("%w: unknown style %d", ErrInvalidOptions, opts.Style)
+	}
+	return opts.Segments.validate()
+}
+
+func (opts Options) borderColor() color.Color {
+	if opts.BorderColor != nil {
+		return opts.BorderColor
+	}
+	if opts.Color != nil {
+		return opts.Color
+	}
+	return opts.Colors[0]
+}
@@ -0,0 +1,164 @@
+package timepng
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"time"
+)
+
+var ErrUnrecognizedImage = errors.New("unrecognized image")
+
+// maxParseScale - наибольший масштаб, который распознает ParseTimePNG
+const maxParseScale = 256
+
+// parseLayouts - форматы, которые распознает ParseTimePNG, по числу символов
+var parseLayouts = map[int]string{
+	len("15:04"):    "15:04",
+	len("15:04:05"): "15:04:05",
+}
+
+// ParseTimePNG распознает время на картинке, созданной TimePNG (или TimeImage
+// с форматом "15:04:05"). Возвращает время, масштаб и цвет символов
+func ParseTimePNG(in io.Reader) (time.Time, int, color.Color, error) {
+	// Размеры проверяются до декодирования: маленький файл может объявить
+	// картинку на гигабайты
+	var header bytes.Buffer
+	config, err := png.DecodeConfig(io.TeeReader(in, &header))
+	if err != nil {
+		return time.Time{}, 0, nil, fmt.Errorf("%w: %v", ErrUnrecognizedImage, err)
+	}
+	if err := checkParseSize(config.Width, config.Height); err != nil {
+		return time.Time{}, 0, nil, err
+	}
+	img, err := png.Decode(io.MultiReader(&header, in))
+	if err != nil {
+		return time.Time{}, 0, nil, fmt.Errorf("%w: %v", ErrUnrecognizedImage, err)
+	}
+	text, scale, c, err := parseTextImage(img)
+	if err != nil {
+		return time.Time{}, 0, nil, err
+	}
+	layout, ok := parseLayouts[len(text)]
+	if !ok {
+		return time.Time{}, 0, nil, fmt.Errorf("%w: unexpected text %q", ErrUnrecognizedImage, text)
+	}
+	t, err := time.Parse(layout, text)
+	if err != nil {
+		return time.Time{}, 0, nil, fmt.Errorf("%w: unexpected text %q", ErrUnrecognizedImage, text)
+	}
+	return t, scale, c, nil
+}
+
+// checkParseSize проверяет, что картинку такого размера могла создать TimePNG
+// для одного из parseLayouts с масштабом не больше maxParseScale
+func checkParseSize(width, height int) error {
+	if height <= 0 || height%5 != 0 || height/5 > maxParseScale {
+		return fmt.Errorf("%w: unexpected height %d", ErrUnrecognizedImage, height)
+	}
+	scale := height / 5
+	for n := range parseLayouts {
+		if width == (4*n-1)*scale {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unexpected width %d for scale %d", ErrUnrecognizedImage, width, scale)
+}
+
+// parseTextImage восстанавливает строку по изображению, построенному как в buildTextImage
+func parseTextImage(img image.Image) (string, int, color.Color, error) {
+	bounds := img.Bounds()
+	if bounds.Dy() == 0 || bounds.Dy()%5 != 0 {
+		return "", 0, nil, fmt.Errorf("%w: height %d is not a multiple of 5", ErrUnrecognizedImage, bounds.Dy())
+	}
+	scale := bounds.Dy() / 5
+	mask_w := bounds.Dx() / scale
+	if bounds.Dx()%scale != 0 || (mask_w+1)%4 != 0 {
+		return "", 0, nil, fmt.Errorf("%w: width %d does not fit glyph grid with scale %d",
+			ErrUnrecognizedImage, bounds.Dx(), scale)
+	}
+
+	var lit *color.RGBA
+	cellAt := func(mask_x, mask_y int) (int, error) {
+		var cell_v = -1
+		for y := 0; y < scale; y++ {
+			for x := 0; x < scale; x++ {
+				px := bounds.Min.X + mask_x*scale + x
+				py := bounds.Min.Y + mask_y*scale + y
+				c := color.RGBAModel.Convert(img.At(px, py)).(color.RGBA)
+				v := 0
+				if c.A != 0 {
+					v = 1
+					if lit == nil {
+						lit = &c
+					} else if *lit != c {
+						return 0, fmt.Errorf("%w: unexpected color at (%d, %d)", ErrUnrecognizedImage, px, py)
+					}
+				}
+				if cell_v != -1 && cell_v != v {
+					return 0, fmt.Errorf("%w: cell (%d, %d) is not uniform", ErrUnrecognizedImage, mask_x, mask_y)
+				}
+				cell_v = v
+			}
+		}
+		return cell_v, nil
+	}
+
+	text := make([]rune, 0, (mask_w+1)/4)
+	for symb_i := 0; symb_i < (mask_w+1)/4; symb_i++ {
+		mask := make([]int, 15)
+		for mask_i := range mask {
+			v, err := cellAt(symb_i*4+mask_i%3, mask_i/3)
+			if err != nil {
+				return "", 0, nil, err
+			}
+			mask[mask_i] = v
+		}
+		if symb_i*4+3 < mask_w {
+			for mask_y := 0; mask_y < 5; mask_y++ {
+				v, err := cellAt(symb_i*4+3, mask_y)
+				if err != nil {
+					return "", 0, nil, err
+				}
+				if v != 0 {
+					return "", 0, nil, fmt.Errorf("%w: glyph separator %d is not empty", ErrUnrecognizedImage, symb_i)
+				}
+			}
+		}
+		symb, ok := matchGlyph(mask)
+		if !ok {
+			return "", 0, nil, fmt.Errorf("%w: unknown glyph %d", ErrUnrecognizedImage, symb_i)
+		}
+		text = append(text, symb)
+	}
+	if lit == nil {
+		return "", 0, nil, fmt.Errorf("%w: image is empty", ErrUnrecognizedImage)
+	}
+	return string(text), scale, *lit, nil
+}
+
+// matchGlyph ищет в `nums` символ с маской `mask`
+func matchGlyph(mask []int) (rune, bool) {
+	for symb, symb_mask := range nums {
+		if equalMasks(mask, symb_mask) {
+			return symb, true
+		}
+	}
+	return 0, false
+}
+
+func equalMasks(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,273 @@
+package timepng
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"time"
+	"unicode"
+)
+
+// TimePNGWithOptions записывает в `out` картинку в формате png со временем `t`,
+// отформатированным по `layout` и оформленным согласно `opts`
+func TimePNGWithOptions(out io.Writer, t time.Time, layout string, opts Options) error {
+	img, err := RenderTime(t, layout, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(out, img)
+}
+
+// RenderTime создает изображение времени `t`, отформатированного по `layout`
+func RenderTime(t time.Time, layout string, opts Options) (*image.RGBA, error) {
+	return RenderText(t.Format(layout), opts)
+}
+
+// RenderText создает изображение строки `text` с оформлением `opts`.
+// Строка может содержать только символы из `nums`
+func RenderText(text string, opts Options) (*image.RGBA, error) {
+	return renderGlyphs(text, opts, timeGlyph)
+}
+
+// renderGlyphs создает изображение строки `text`, маски символов берутся из `lookup`
+func renderGlyphs(text string, opts Options, lookup func(rune) ([]int, bool)) (*image.RGBA, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	masks, err := glyphMasks(text, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := opts.Padding + opts.Border
+	glyph_w := 3 * opts.Scale
+	width := 2*offset + len(masks)*glyph_w + (len(masks)-1)*opts.Gap
+	height := 2*offset + 5*opts.Scale
+	res_img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	if opts.Background != nil {
+		draw.Draw(res_img, res_img.Rect, image.NewUniform(opts.Background), image.Point{}, draw.Src)
+	}
+	if opts.Border > 0 {
+		drawBorder(res_img, opts.Border, opts.borderColor())
+	}
+
+	text_rect := image.Rect(offset, offset, width-offset, height-offset)
+	cell := cellMask(opts.Shape, opts.Scale)
+	srcs := make([]image.Image, len(masks))
+	spans := make([][]byte, len(masks))
+	for symb_i := range masks {
+		srcs[symb_i] = opts.glyphSource(symb_i, text_rect)
+		// Непрозрачные квадратные клетки быстрее заполнять копированием строк
+		if u, ok := srcs[symb_i].(*image.Uniform); ok && cell == nil && isOpaque(u.C) {
+			spans[symb_i] = colorSpan(u.C, opts.Scale)
+		}
+	}
+
+	if opts.Style != StyleMask {
+		drawSegmentGlyphs(res_img, text, opts, srcs, offset)
+		return res_img, nil
+	}
+
+	parallelRows(5, width*opts.Scale, func(from, to int) {
+		for symb_i, mask := range masks {
+			if opts.HideColon && isColon(mask) {
+				continue
+			}
+			x0 := offset + symb_i*(glyph_w+opts.Gap)
+			for mask_y := from; mask_y < to; mask_y++ {
+				for mask_x := 0; mask_x < 3; mask_x++ {
+					if mask[mask_y*3+mask_x] != 1 {
+						continue
+					}
+					cell_min := image.Pt(x0+mask_x*opts.Scale, offset+mask_y*opts.Scale)
+					cell_rect := image.Rectangle{Min: cell_min, Max: cell_min.Add(image.Pt(opts.Scale, opts.Scale))}
+					switch {
+					case spans[symb_i] != nil:
+						fillRect(res_img, cell_rect, spans[symb_i])
+					case cell == nil:
+						draw.Draw(res_img, cell_rect, srcs[symb_i], cell_min, draw.Over)
+					default:
+						draw.DrawMask(res_img, cell_rect, srcs[symb_i], cell_min, cell, image.Point{}, draw.Over)
+					}
+				}
+			}
+		}
+	})
+	return res_img, nil
+}
+
+// drawSegmentGlyphs рисует символы строки `text` в стиле сегментного индикатора
+func drawSegmentGlyphs(img *image.RGBA, text string, opts Options, srcs []image.Image, offset int) {
+	type segmentPair struct {
+		on, off *image.Alpha
+	}
+	cache := make(map[rune]segmentPair)
+	var off_src image.Image
+	if opts.Segments.OffColor != nil {
+		off_src = image.NewUniform(opts.Segments.OffColor)
+	}
+
+	for symb_i, symb := range []rune(text) {
+		masks, ok := cache[symb]
+		if !ok {
+			masks.on, masks.off = segmentMasks(opts, symb, opts.HideColon)
+			cache[symb] = masks
+		}
+		glyph_min := image.Pt(offset+symb_i*(3*opts.Scale+opts.Gap), offset)
+		glyph_rect := masks.on.Rect.Add(glyph_min)
+		if off_src != nil {
+			draw.DrawMask(img, glyph_rect, off_src, image.Point{}, masks.off, image.Point{}, draw.Over)
+		}
+		draw.DrawMask(img, glyph_rect, srcs[symb_i], glyph_min, masks.on, image.Point{}, draw.Over)
+	}
+}
+
+func isColon(mask []int) bool {
+	return equalMasks(mask, nums[':'])
+}
+
+func isOpaque(c color.Color) bool {
+	_, _, _, a := c.RGBA()
+	return a == 0xffff
+}
+
+// glyphMasks возвращает маски всех символов строки `text`
+func glyphMasks(text string, lookup func(rune) ([]int, bool)) ([][]int, error) {
+	var masks [][]int
+	for _, symb := range text {
+		mask, ok := lookup(symb)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedSymbol, symb)
+		}
+		masks = append(masks, mask)
+	}
+	if len(masks) == 0 {
+		return nil, ErrEmptyText
+	}
+	return masks, nil
+}
+
+// timeGlyph ищет маску символа среди цифр и разделителя
+func timeGlyph(symb rune) ([]int, bool) {
+	mask, ok := nums[symb]
+	return mask, ok
+}
+
+// labelGlyph ищет маску символа среди цифр и букв
+func labelGlyph(symb rune) ([]int, bool) {
+	if mask, ok := nums[symb]; ok {
+		return mask, true
+	}
+	mask, ok := letters[unicode.ToUpper(symb)]
+	return mask, ok
+}
+
+// glyphSource возвращает источник цвета для символа с номером `symb_i`
+func (opts Options) glyphSource(symb_i int, text_rect image.Rectangle) image.Image {
+	switch {
+	case len(opts.Colors) > 0:
+		return image.NewUniform(opts.Colors[symb_i%len(opts.Colors)])
+	case opts.GradientTo != nil:
+		return newLinearGradient(opts.Color, opts.GradientTo, text_rect.Min.X, text_rect.Max.X)
+	default:
+		return image.NewUniform(opts.Color)
+	}
+}
+
+// drawBorder рисует по краю изображения рамку толщины `border`
+func drawBorder(img *image.RGBA, border int, c color.Color) {
+	src := image.NewUniform(c)
+	r := img.Rect
+	for _, side := range []image.Rectangle{
+		image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+border),
+		image.Rect(r.Min.X, r.Max.Y-border, r.Max.X, r.Max.Y),
+		image.Rect(r.Min.X, r.Min.Y+border, r.Min.X+border, r.Max.Y-border),
+		image.Rect(r.Max.X-border, r.Min.Y+border, r.Max.X, r.Max.Y-border),
+	} {
+		draw.Draw(img, side, src, image.Point{}, draw.Src)
+	}
+}
+
+// cellSamples - число подвыборок по каждой оси при сглаживании клетки
+const cellSamples = 4
+
+// cellMask возвращает маску прозрачности для одной клетки размера `size`.
+// Для ShapeSquare возвращает nil: клетка закрашивается целиком
+func cellMask(shape Shape, size int) *image.Alpha {
+	if shape == ShapeSquare {
+		return nil
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, size, size))
+	half := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			inside := 0
+			for sy := 0; sy < cellSamples; sy++ {
+				for sx := 0; sx < cellSamples; sx++ {
+					px := float64(x) + (float64(sx)+0.5)/cellSamples - half
+					py := float64(y) + (float64(sy)+0.5)/cellSamples - half
+					if insideShape(shape, px, py, half) {
+						inside++
+					}
+				}
+			}
+			mask.SetAlpha(x, y, color.Alpha{A: uint8(inside * 255 / (cellSamples * cellSamples))})
+		}
+	}
+	return mask
+}
+
+// insideShape проверяет, лежит ли точка (x, y) внутри фигуры с центром в нуле
+// и полуразмером `half`
+func insideShape(shape Shape, x, y, half float64) bool {
+	switch shape {
+	case ShapeCircle:
+		return x*x+y*y <= half*half
+	case ShapeRoundedSquare:
+		radius := half / 2
+		dx := math.Max(math.Abs(x)-(half-radius), 0)
+		dy := math.Max(math.Abs(y)-(half-radius), 0)
+		return dx*dx+dy*dy <= radius*radius
+	default:
+		return true
+	}
+}
+
+// linearGradient - горизонтальный градиент от `from` (столбец x0) до `to` (столбец x1-1)
+type linearGradient struct {
+	from, to [4]uint32
+	x0, x1   int
+}
+
+func newLinearGradient(from, to color.Color, x0, x1 int) *linearGradient {
+	g := &linearGradient{x0: x0, x1: x1}
+	g.from[0], g.from[1], g.from[2], g.from[3] = from.RGBA()
+	g.to[0], g.to[1], g.to[2], g.to[3] = to.RGBA()
+	return g
+}
+
+func (g *linearGradient) ColorModel() color.Model {
+	return color.RGBA64Model
+}
+
+func (g *linearGradient) Bounds() image.Rectangle {
+	return image.Rect(-1e9, -1e9, 1e9, 1e9)
+}
+
+func (g *linearGradient) At(x, y int) color.Color {
+	var t float64
+	if g.x1-1 > g.x0 {
+		t = float64(x-g.x0) / float64(g.x1-1-g.x0)
+	}
+	t = math.Min(math.Max(t, 0), 1)
+	var c [4]uint16
+	for i := range c {
+		c[i] = uint16(math.Round(float64(g.from[i]) + (float64(g.to[i])-float64(g.from[i]))*t))
+	}
+	return color.RGBA64{R: c[0], G: c[1], B: c[2], A: c[3]}
+}
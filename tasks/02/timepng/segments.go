@@ -0,0 +1,292 @@
+package timepng
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// Style задает способ отрисовки символов
+type Style int
+
+const (
+	// StyleMask - символы рисуются по маскам 3x5 из `nums`
+	StyleMask Style = iota
+	// StyleSegments7 - семисегментный индикатор
+	StyleSegments7
+	// StyleSegments14 - четырнадцатисегментный индикатор
+	StyleSegments14
+	// StyleSegments16 - шестнадцатисегментный индикатор
+	StyleSegments16
+)
+
+// defaultThickness - толщина сегмента по умолчанию в долях ширины символа
+const defaultThickness = 0.2
+
+// SegmentOptions задает внешний вид сегментных индикаторов
+type SegmentOptions struct {
+	// Thickness - толщина сегмента в долях ширины символа, 0 означает значение по умолчанию
+	Thickness float64
+	// Slant - наклон символа: сдвиг верхнего края вправо в долях высоты символа
+	Slant float64
+	// OffColor - цвет выключенных сегментов (как на настоящем индикаторе),
+	// nil - выключенные сегменты не рисуются
+	OffColor color.Color
+}
+
+func (opts SegmentOptions) validate() error {
+	if opts.Thickness < 0 || opts.Thickness > 0.5 {
+		return fmt.Errorf("%w: segment thickness %v", ErrInvalidOptions, opts.Thickness)
+	}
+	if opts.Slant < -0.5 || opts.Slant > 0.5 {
+		return fmt.Errorf("%w: segment slant %v", ErrInvalidOptions, opts.Slant)
+	}
+	return nil
+}
+
+// segmentDigits - включенные сегменты семисегментного индикатора для цифр.
+// Сегменты: a - верхний, b, c - правые, d - нижний, e, f - левые, g - средний
+var segmentDigits = map[rune]string{
+	'0': "abcdef",
+	'1': "bc",
+	'2': "abdeg",
+	'3': "abcdg",
+	'4': "bcfg",
+	'5': "acdfg",
+	'6': "acdefg",
+	'7': "abc",
+	'8': "abcdefg",
+	'9': "abcdfg",
+}
+
+// segmentNames возвращает все сегменты стиля `style`.
+// В 14 сегментах средний сегмент делится на g1 и g2 и добавляются диагонали
+// (tl, tr, bl, br) и центральные вертикали (tc, bc). В 16 сегментах
+// дополнительно делятся верхний (a1, a2) и нижний (d1, d2) сегменты
+func segmentNames(style Style) []string {
+	switch style {
+	case StyleSegments14:
+		return strings.Fields("a b c d e f g1 g2 tl tc tr bl bc br")
+	case StyleSegments16:
+		return strings.Fields("a1 a2 b c d1 d2 e f g1 g2 tl tc tr bl bc br")
+	default:
+		return strings.Fields("a b c d e f g")
+	}
+}
+
+// litSegments возвращает включенные сегменты цифры `symb` для стиля `style`
+func litSegments(style Style, symb rune) map[string]bool {
+	lit := make(map[string]bool)
+	for _, name := range segmentDigits[symb] {
+		seg := string(name)
+		switch {
+		case style != StyleSegments7 && seg == "g":
+			lit["g1"], lit["g2"] = true, true
+		case style == StyleSegments16 && (seg == "a" || seg == "d"):
+			lit[seg+"1"], lit[seg+"2"] = true, true
+		default:
+			lit[seg] = true
+		}
+	}
+	// Перечеркнутый ноль, чтобы отличать его от буквы O
+	if symb == '0' && style != StyleSegments7 {
+		lit["tr"], lit["bl"] = true, true
+	}
+	return lit
+}
+
+type point struct {
+	x, y float64
+}
+
+// segmentGeometry строит многоугольники сегментов в символе размера `w` x `h`
+type segmentGeometry struct {
+	w, h      float64
+	thickness float64
+	slant     float64
+}
+
+func newSegmentGeometry(opts Options) segmentGeometry {
+	thickness := opts.Segments.Thickness
+	if thickness == 0 {
+		thickness = defaultThickness
+	}
+	w := float64(3 * opts.Scale)
+	return segmentGeometry{
+		w:         w,
+		h:         float64(5 * opts.Scale),
+		thickness: thickness * w,
+		slant:     opts.Segments.Slant,
+	}
+}
+
+// segment возвращает шестиугольник сегмента с заостренными концами по оси `name`
+func (g segmentGeometry) segment(name string) []point {
+	half := g.thickness / 2
+	l, c, r := half, g.w/2, g.w-half
+	t, m, b := half, g.h/2, g.h-half
+	lines := map[string][2]point{
+		"a":  {{l, t}, {r, t}},
+		"a1": {{l, t}, {c, t}},
+		"a2": {{c, t}, {r, t}},
+		"b":  {{r, t}, {r, m}},
+		"c":  {{r, m}, {r, b}},
+		"d":  {{l, b}, {r, b}},
+		"d1": {{l, b}, {c, b}},
+		"d2": {{c, b}, {r, b}},
+		"e":  {{l, m}, {l, b}},
+		"f":  {{l, t}, {l, m}},
+		"g":  {{l, m}, {r, m}},
+		"g1": {{l, m}, {c, m}},
+		"g2": {{c, m}, {r, m}},
+		"tl": {{l, t}, {c, m}},
+		"tc": {{c, t}, {c, m}},
+		"tr": {{r, t}, {c, m}},
+		"bl": {{c, m}, {l, b}},
+		"bc": {{c, m}, {c, b}},
+		"br": {{c, m}, {r, b}},
+	}
+	line := lines[name]
+	p0, p1 := line[0], line[1]
+	length := math.Hypot(p1.x-p0.x, p1.y-p0.y)
+	dx, dy := (p1.x-p0.x)/length, (p1.y-p0.y)/length
+	nx, ny := -dy, dx
+
+	// Зазор между соседними сегментами, диагонали укорачиваются сильнее
+	gap := g.thickness * 0.15
+	if dx != 0 && dy != 0 {
+		gap = g.thickness * 0.8
+	}
+	p0 = point{p0.x + dx*gap, p0.y + dy*gap}
+	p1 = point{p1.x - dx*gap, p1.y - dy*gap}
+	tip := math.Min(half, (length-2*gap)/2)
+
+	return g.transform([]point{
+		p0,
+		{p0.x + dx*tip + nx*half, p0.y + dy*tip + ny*half},
+		{p1.x - dx*tip + nx*half, p1.y - dy*tip + ny*half},
+		p1,
+		{p1.x - dx*tip - nx*half, p1.y - dy*tip - ny*half},
+		{p0.x + dx*tip - nx*half, p0.y + dy*tip - ny*half},
+	})
+}
+
+// colonDot возвращает квадратную точку двоеточия с центром на высоте `y`
+func (g segmentGeometry) colonDot(y float64) []point {
+	half := g.thickness / 2
+	c := g.w / 2
+	return g.transform([]point{
+		{c - half, y - half},
+		{c + half, y - half},
+		{c + half, y + half},
+		{c - half, y + half},
+	})
+}
+
+// transform применяет к многоугольнику наклон, оставляя его внутри символа
+func (g segmentGeometry) transform(poly []point) []point {
+	if g.slant == 0 {
+		return poly
+	}
+	shift := math.Abs(g.slant) * g.h
+	res := make([]point, len(poly))
+	for i, p := range poly {
+		x := p.x * (g.w - shift) / g.w
+		if g.slant > 0 {
+			x += shift * (g.h - p.y) / g.h
+		} else {
+			x += shift * p.y / g.h
+		}
+		res[i] = point{x, p.y}
+	}
+	return res
+}
+
+// segmentMasks возвращает маски включенных и выключенных сегментов символа `symb`
+func segmentMasks(opts Options, symb rune, hide_colon bool) (*image.Alpha, *image.Alpha) {
+	geom := newSegmentGeometry(opts)
+	rect := image.Rect(0, 0, 3*opts.Scale, 5*opts.Scale)
+	on, off := image.NewAlpha(rect), image.NewAlpha(rect)
+
+	if symb == ':' {
+		dst := on
+		if hide_colon {
+			dst = off
+		}
+		fillPolygon(dst, geom.colonDot(geom.h/3))
+		fillPolygon(dst, geom.colonDot(geom.h*2/3))
+		return on, off
+	}
+
+	lit := litSegments(opts.Style, symb)
+	for _, name := range segmentNames(opts.Style) {
+		if lit[name] {
+			fillPolygon(on, geom.segment(name))
+		} else {
+			fillPolygon(off, geom.segment(name))
+		}
+	}
+	return on, off
+}
+
+// fillPolygon добавляет в маску `mask` сглаженный выпуклый многоугольник `poly`
+func fillPolygon(mask *image.Alpha, poly []point) {
+	min_p, max_p := poly[0], poly[0]
+	for _, p := range poly {
+		min_p = point{math.Min(min_p.x, p.x), math.Min(min_p.y, p.y)}
+		max_p = point{math.Max(max_p.x, p.x), math.Max(max_p.y, p.y)}
+	}
+	bounds := image.Rect(
+		int(math.Floor(min_p.x)), int(math.Floor(min_p.y)),
+		int(math.Ceil(max_p.x)), int(math.Ceil(max_p.y)),
+	).Intersect(mask.Rect)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			inside := 0
+			for sy := 0; sy < cellSamples; sy++ {
+				for sx := 0; sx < cellSamples; sx++ {
+					p := point{
+						float64(x) + (float64(sx)+0.5)/cellSamples,
+						float64(y) + (float64(sy)+0.5)/cellSamples,
+					}
+					if insideConvex(poly, p) {
+						inside++
+					}
+				}
+			}
+			if inside == 0 {
+				continue
+			}
+			a := int(mask.AlphaAt(x, y).A) + inside*255/(cellSamples*cellSamples)
+			if a > 255 {
+				a = 255
+			}
+			mask.SetAlpha(x, y, color.Alpha{A: uint8(a)})
+		}
+	}
+}
+
+// insideConvex проверяет, что точка `p` лежит внутри выпуклого многоугольника `poly`
+func insideConvex(poly []point, p point) bool {
+	sign := 0
+	for i := range poly {
+		a, b := poly[i], poly[(i+1)%len(poly)]
+		cross := (b.x-a.x)*(p.y-a.y) - (b.y-a.y)*(p.x-a.x)
+		switch {
+		case cross > 0:
+			if sign < 0 {
+				return false
+			}
+			sign = 1
+		case cross < 0:
+			if sign > 0 {
+				return false
+			}
+			sign = -1
+		}
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package timepng
 
 import (
+	"errors"
 	"image"
 	"image/color"
 	"image/png"
@@ -8,32 +9,56 @@ import (
 	"time"
 )
 
+var (
+	ErrUnsupportedSymbol = errors.New("unsupported symbol")
+	ErrEmptyText         = errors.New("empty text")
+)
+
 // TimePNG записывает в `out` картинку в формате png с текущим временем
 func TimePNG(out io.Writer, t time.Time, c color.Color, scale int) {
 	png.Encode(out, buildTimeImage(t, c, scale))
 }
 
+// TimeImage создает изображение времени `t`, отформатированного по `layout`.
+// Результат форматирования может содержать только символы из `nums`
+func TimeImage(t time.Time, layout string, c color.Color, scale int) (*image.RGBA, error) {
+	return RenderTime(t, layout, DefaultOptions(c, scale))
+}
+
 // buildTimeImage создает новое изображение с временем `t`
 func buildTimeImage(t time.Time, c color.Color, scale int) *image.RGBA {
-	f_time := t.Format("15:04")
-	full_mask := make([]int, len(f_time)*20-5)
-	for symb_i, symb := range f_time {
-		for mask_i, mask_v := range nums[symb] {
+	res_img, _ := buildTextImage(t.Format("15:04"), c, scale)
+	return res_img
+}
+
+// buildTextImage создает новое изображение со строкой `text`
+func buildTextImage(text string, c color.Color, scale int) (*image.RGBA, error) {
+	full_mask, width, err := buildMask(text)
+	if err != nil {
+		return nil, err
+	}
+	res_img := image.NewRGBA(image.Rect(0, 0, width*scale, 5*scale))
+	fillWithMask(res_img, full_mask, c, scale)
+	return res_img, nil
+}
+
+// buildMask собирает общую маску для строки `text`: символы шириной 3 клетки
+// разделяются пустым столбцом. Возвращает маску и ее ширину
+func buildMask(text string) ([]int, int, error) {
+	masks, err := glyphMasks(text, timeGlyph)
+	if err != nil {
+		return nil, 0, err
+	}
+	width := 4*len(masks) - 1
+	full_mask := make([]int, width*5)
+	for symb_i, mask := range masks {
+		for mask_i, mask_v := range mask {
 			mask_x := mask_i % 3
 			mask_y := mask_i / 3
-			full_mask[symb_i*4+mask_x+(mask_y*4*(len(f_time)-1)+mask_y*3)] = mask_v
-		}
-		if symb_i < len(f_time)-1 {
-			full_mask[symb_i*4+3] = 0
-			full_mask[symb_i*4+22] = 0
-			full_mask[symb_i*4+42] = 0
-			full_mask[symb_i*4+62] = 0
-			full_mask[symb_i*4+82] = 0
+			full_mask[symb_i*4+mask_x+mask_y*width] = mask_v
 		}
 	}
-	res_img := image.NewRGBA(image.Rect(0, 0, 4*scale*(len(f_time)-1)+3*scale, 5*scale))
-	fillWithMask(res_img, full_mask, c, scale)
-	return res_img
+	return full_mask, width, nil
 }
 
 // fillWithMask заполняет изображение `img` цветом `c` по маске `mask`. Маска `mask`
@@ -41,26 +66,20 @@ func buildTimeImage(t time.Time, c color.Color, scale int) *image.RGBA {
 // NOTE: Так как это вспомогательная функция, можно считать, что mask имеет размер (3x5)
 func fillWithMask(img *image.RGBA, mask []int, c color.Color, scale int) {
 	width := img.Rect.Dx()
-	r, g, b, a := c.RGBA()
+	mask_w := width / scale
+	span := colorSpan(c, scale)
 
-	for i, _ := range img.Pix {
-		point_x := i / 4 % width
-		point_y := i / 4 / width
-		mask_x := point_x / scale
-		mask_y := point_y / scale
-		if mask[mask_x+mask_y*(width/scale)] == 1 {
-			switch i % 4 {
-			case 0:
-				img.Pix[i] = uint8(r / 256)
-			case 1:
-				img.Pix[i] = uint8(g / 256)
-			case 2:
-				img.Pix[i] = uint8(b / 256)
-			case 3:
-				img.Pix[i] = uint8(a / 256)
+	parallelRows(img.Rect.Dy(), width, func(from, to int) {
+		for y := from; y < to; y++ {
+			row := img.Pix[y*img.Stride:]
+			mask_row := mask[y/scale*mask_w : (y/scale+1)*mask_w]
+			for mask_x, mask_v := range mask_row {
+				if mask_v == 1 {
+					copy(row[mask_x*len(span):], span)
+				}
 			}
 		}
-	}
+	})
 }
 
 var nums = map[rune][]int{
@@ -2,10 +2,17 @@ package timepng
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
 	"image"
 	"image/color"
+	"image/draw"
+	"image/gif"
 	"image/png"
 	"io/ioutil"
+	"math/rand"
+	"os"
 	"testing"
 	"time"
 
@@ -114,4 +121,491 @@ func parseTime(tStr string) time.Time {
 		panic("invalid tests")
 	}
 	return t
-}
\ No newline at end of file
+}
+
+func TestTimeImage(t *testing.T) {
+	img, err := TimeImage(parseTime("20:48"), "15:04:05", color.Black, 2)
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 31*2, 5*2), img.Rect)
+
+	_, err = TimeImage(parseTime("20:48"), "Mon 15:04", color.Black, 2)
+	require.ErrorIs(t, err, ErrUnsupportedSymbol)
+
+	_, err = TimeImage(parseTime("20:48"), "", color.Black, 2)
+	require.ErrorIs(t, err, ErrEmptyText)
+}
+
+func TestRenderTime_Defaults(t *testing.T) {
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			img, err := RenderTime(tc.Time, "15:04", DefaultOptions(tc.Color, scale))
+			require.NoError(t, err)
+			golden := loadGolden(t, tc.File)
+			require.Equal(t, golden.Rect, img.Rect)
+			require.Equal(t, golden.Pix, img.Pix)
+		})
+	}
+}
+
+func TestRenderText_Layout(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	bg := color.RGBA{B: 255, A: 255}
+	border := color.RGBA{G: 255, A: 255}
+	opts := DefaultOptions(red, 2)
+	opts.Background = bg
+	opts.Padding = 3
+	opts.Gap = 5
+	opts.Border = 1
+	opts.BorderColor = border
+
+	img, err := RenderText("11", opts)
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 2*4+2*3*2+5, 2*4+5*2), img.Rect)
+
+	require.Equal(t, border, img.At(0, 0))
+	require.Equal(t, border, img.At(img.Rect.Dx()-1, img.Rect.Dy()-1))
+	require.Equal(t, bg, img.At(1, 1))
+	// Левая верхняя клетка единицы пустая, средняя закрашена
+	require.Equal(t, bg, img.At(4, 4))
+	require.Equal(t, red, img.At(4+2, 4))
+	// Промежуток между символами
+	require.Equal(t, bg, img.At(4+6+2, 4+2))
+	require.Equal(t, red, img.At(4+6+5+2, 4))
+}
+
+func TestRenderText_Colors(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	green := color.RGBA{G: 255, A: 255}
+	opts := DefaultOptions(nil, 1)
+	opts.Colors = []color.Color{red, green}
+
+	img, err := RenderText("888", opts)
+	require.NoError(t, err)
+	require.Equal(t, red, img.At(0, 0))
+	require.Equal(t, green, img.At(4, 0))
+	require.Equal(t, red, img.At(8, 0))
+
+	opts = DefaultOptions(red, 1)
+	opts.GradientTo = green
+	img, err = RenderText("88", opts)
+	require.NoError(t, err)
+	require.Equal(t, red, img.At(0, 0))
+	require.Equal(t, green, img.At(6, 0))
+	require.Equal(t, color.RGBA{}, img.RGBAAt(3, 0))
+	mid := img.RGBAAt(2, 0)
+	require.True(t, mid.R > 0 && mid.G > 0 && mid.R > mid.G)
+}
+
+func TestRenderText_Shapes(t *testing.T) {
+	c := color.RGBA{R: 255, A: 255}
+	for _, shape := range []Shape{ShapeCircle, ShapeRoundedSquare} {
+		opts := DefaultOptions(c, 16)
+		opts.Shape = shape
+		img, err := RenderText("8", opts)
+		require.NoError(t, err)
+		require.Equal(t, c, img.At(8, 8))
+		require.Equal(t, uint8(0), img.RGBAAt(0, 0).A)
+		edge := img.RGBAAt(1, 3).A
+		if shape == ShapeCircle {
+			require.True(t, edge > 0 && edge < 255, "expected anti-aliased edge, got %d", edge)
+		}
+	}
+}
+
+func TestRenderText_Errors(t *testing.T) {
+	_, err := RenderText("12", Options{Color: color.Black})
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = RenderText("12", Options{Scale: 1})
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	opts := DefaultOptions(color.Black, 1)
+	opts.Padding = -1
+	_, err = RenderText("12", opts)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = RenderText("a", DefaultOptions(color.Black, 1))
+	require.ErrorIs(t, err, ErrUnsupportedSymbol)
+}
+
+func loadGolden(t *testing.T, file string) *image.RGBA {
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	src, err := png.Decode(f)
+	require.NoError(t, err)
+	img := image.NewRGBA(src.Bounds())
+	draw.Draw(img, img.Rect, src, src.Bounds().Min, draw.Src)
+	return img
+}
+
+func TestBuildTimeImage_Pixels(t *testing.T) {
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			img := buildTimeImage(tc.Time, tc.Color, scale)
+			golden := loadGolden(t, tc.File)
+			require.Equal(t, golden.Rect, img.Rect)
+			require.Equal(t, golden.Pix, img.Pix)
+		})
+	}
+}
+
+func TestFillWithMask_Naive(t *testing.T) {
+	defer func(old int) {
+		parallelPixels = old
+	}(parallelPixels)
+
+	rnd := rand.New(rand.NewSource(1))
+	c := color.RGBA{R: 10, G: 20, B: 30, A: 40}
+	for _, threshold := range []int{parallelPixels, 1} {
+		parallelPixels = threshold
+		for _, sc := range []int{1, 3, 16} {
+			mask := make([]int, 19*5)
+			for i := range mask {
+				mask[i] = rnd.Intn(2)
+			}
+			want := image.NewRGBA(image.Rect(0, 0, 19*sc, 5*sc))
+			fillWithMaskNaive(want, mask, c, sc)
+			got := image.NewRGBA(want.Rect)
+			fillWithMask(got, mask, c, sc)
+			require.Equal(t, want.Pix, got.Pix, "scale %d, threshold %d", sc, threshold)
+		}
+	}
+}
+
+func BenchmarkFillWithMask(b *testing.B) {
+	mask, width, err := buildMask("23:59")
+	require.NoError(b, err)
+	for _, sc := range []int{10, 100, 400} {
+		img := image.NewRGBA(image.Rect(0, 0, width*sc, 5*sc))
+		b.Run(fmt.Sprintf("naive/scale=%d", sc), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				fillWithMaskNaive(img, mask, color.Black, sc)
+			}
+		})
+		b.Run(fmt.Sprintf("rows/scale=%d", sc), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				fillWithMask(img, mask, color.Black, sc)
+			}
+		})
+	}
+}
+
+func BenchmarkRenderText(b *testing.B) {
+	for _, sc := range []int{10, 100, 400} {
+		opts := DefaultOptions(color.Black, sc)
+		b.Run(fmt.Sprintf("scale=%d", sc), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = RenderText("23:59", opts)
+			}
+		})
+	}
+}
+
+// fillWithMaskNaive - исходная попиксельная реализация fillWithMask,
+// используется для проверки результата и сравнения скорости
+func fillWithMaskNaive(img *image.RGBA, mask []int, c color.Color, scale int) {
+	width := img.Rect.Dx()
+	r, g, b, a := c.RGBA()
+
+	for i := range img.Pix {
+		point_x := i / 4 % width
+		point_y := i / 4 / width
+		mask_x := point_x / scale
+		mask_y := point_y / scale
+		if mask[mask_x+mask_y*(width/scale)] == 1 {
+			switch i % 4 {
+			case 0:
+				img.Pix[i] = uint8(r / 256)
+			case 1:
+				img.Pix[i] = uint8(g / 256)
+			case 2:
+				img.Pix[i] = uint8(b / 256)
+			case 3:
+				img.Pix[i] = uint8(a / 256)
+			}
+		}
+	}
+}
+
+func TestParseTimePNG_RoundTrip(t *testing.T) {
+	c := color.RGBA{R: 100, G: 100, B: 255, A: 255}
+	start := parseTime("00:00")
+	for minute := 0; minute < 24*60; minute++ {
+		want := start.Add(time.Duration(minute) * time.Minute)
+		var b bytes.Buffer
+		TimePNG(&b, want, c, 1+minute%3)
+
+		got, sc, gotColor, err := ParseTimePNG(&b)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+		require.Equal(t, 1+minute%3, sc)
+		require.Equal(t, c, gotColor)
+	}
+}
+
+func TestParseTimePNG_Golden(t *testing.T) {
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			f, err := os.Open(tc.File)
+			require.NoError(t, err)
+			defer func() {
+				_ = f.Close()
+			}()
+			got, sc, c, err := ParseTimePNG(f)
+			require.NoError(t, err)
+			require.Equal(t, tc.Time, got)
+			require.Equal(t, scale, sc)
+			require.Equal(t, tc.Color, c)
+		})
+	}
+}
+
+func TestParseTimePNG_Seconds(t *testing.T) {
+	want, err := time.Parse("15:04:05", "07:08:09")
+	require.NoError(t, err)
+	var b bytes.Buffer
+	require.NoError(t, TimePNGWithOptions(&b, want, "15:04:05", DefaultOptions(color.White, 2)))
+	got, _, _, err := ParseTimePNG(&b)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+// withSize подменяет размеры в заголовке IHDR картинки, не меняя данных
+func withSize(t *testing.T, b *bytes.Buffer, width, height int) *bytes.Buffer {
+	raw := b.Bytes()
+	// Сигнатура (8 байт), длина и тип чанка (8 байт), затем ширина и высота
+	require.Equal(t, "IHDR", string(raw[12:16]))
+	binary.BigEndian.PutUint32(raw[16:20], uint32(width))
+	binary.BigEndian.PutUint32(raw[20:24], uint32(height))
+	binary.BigEndian.PutUint32(raw[29:33], crc32.ChecksumIEEE(raw[12:29]))
+	return b
+}
+
+func TestParseTimePNG_Errors(t *testing.T) {
+	encode := func(img image.Image) *bytes.Buffer {
+		var b bytes.Buffer
+		require.NoError(t, png.Encode(&b, img))
+		return &b
+	}
+	valid := func() *image.RGBA {
+		return buildTimeImage(parseTime("12:34"), color.Black, 2)
+	}
+
+	broken := valid()
+	broken.Set(0, 0, color.White)
+	notUniform := valid()
+	notUniform.Set(4*2, 1, color.RGBA{})
+	separator := valid()
+	separator.Set(3*2, 0, color.Black)
+	unknown := valid()
+	draw.Draw(unknown, image.Rect(0, 0, 6, 10), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	for name, in := range map[string]*bytes.Buffer{
+		"not png":     bytes.NewBufferString("hello"),
+		"height":      encode(image.NewRGBA(image.Rect(0, 0, 19, 7))),
+		"width":       encode(image.NewRGBA(image.Rect(0, 0, 20, 5))),
+		"empty":       encode(image.NewRGBA(image.Rect(0, 0, 19, 5))),
+		"color":       encode(broken),
+		"not uniform": encode(notUniform),
+		"separator":   encode(separator),
+		"glyph":       encode(unknown),
+		"huge":        withSize(t, encode(valid()), 31*100000, 5*100000),
+		"scale":       withSize(t, encode(valid()), 19*(maxParseScale+1), 5*(maxParseScale+1)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, _, err := ParseTimePNG(in)
+			require.ErrorIs(t, err, ErrUnrecognizedImage)
+		})
+	}
+}
+
+func TestRenderWorldClock(t *testing.T) {
+	// Понедельник, 12:00 UTC: 15:00 в Москве, 13:00 в Лондоне, 08:00 в Нью-Йорке
+	now := time.Date(2022, 5, 2, 12, 0, 0, 0, time.UTC)
+	red := color.RGBA{R: 255, A: 255}
+	cellBg := color.RGBA{R: 10, G: 10, B: 10, A: 255}
+	clocks := []Clock{
+		{Label: "Moscow", Zone: "Europe/Moscow", Color: red},
+		{Label: "London", Zone: "Europe/London"},
+		{Label: "New York", Zone: "America/New_York", Background: cellBg},
+	}
+	opts := DefaultWorldClockOptions()
+	opts.Columns = 2
+	opts.Scale = 2
+	opts.LabelScale = 1
+	opts.Padding = 2
+	opts.Spacing = 1
+
+	img, err := RenderWorldClock(now, clocks, opts)
+	require.NoError(t, err)
+
+	// Ширина ячейки определяется подписью "New York": 8 символов, отступ и индикатор
+	content_w := (4*8 - 1) + opts.Padding + 5
+	cell_w := 2*opts.Padding + content_w
+	cell_h := 3*opts.Padding + 5 + 5*opts.Scale
+	require.Equal(t, image.Rect(0, 0, 1+2*(cell_w+1), 1+2*(cell_h+1)), img.Rect)
+
+	cellOrigin := func(i int) image.Point {
+		return image.Pt(1+i%2*(cell_w+1)+opts.Padding, 1+i/2*(cell_h+1)+opts.Padding)
+	}
+	indicatorAt := func(i int) color.RGBA {
+		p := cellOrigin(i)
+		return img.RGBAAt(p.X+content_w-1, p.Y)
+	}
+	require.Equal(t, opts.WorkColor, indicatorAt(0))
+	require.Equal(t, opts.WorkColor, indicatorAt(1))
+	require.Equal(t, opts.OffColor, indicatorAt(2))
+
+	// "M" в подписи Москвы закрашена цветом часов, "L" у Лондона - цветом по умолчанию
+	require.Equal(t, red, img.RGBAAt(cellOrigin(0).X, cellOrigin(0).Y))
+	require.Equal(t, opts.Color, img.RGBAAt(cellOrigin(1).X, cellOrigin(1).Y))
+	// Фон ячейки Нью-Йорка
+	require.Equal(t, cellBg, img.RGBAAt(cellOrigin(2).X-1, cellOrigin(2).Y-1))
+
+	// Время в Москве - "15:00", первая клетка единицы пустая, вторая закрашена
+	timeY := cellOrigin(0).Y + 5 + opts.Padding
+	require.Equal(t, color.RGBA{}, img.RGBAAt(cellOrigin(0).X, timeY))
+	require.Equal(t, red, img.RGBAAt(cellOrigin(0).X+opts.Scale, timeY))
+}
+
+func TestRenderWorldClock_Errors(t *testing.T) {
+	opts := DefaultWorldClockOptions()
+	_, err := RenderWorldClock(time.Now(), nil, opts)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+
+	_, err = RenderWorldClock(time.Now(), []Clock{{Label: "Mars", Zone: "Mars/Olympus"}}, opts)
+	require.Error(t, err)
+
+	_, err = RenderWorldClock(time.Now(), []Clock{{Label: "Moscow!", Zone: "Europe/Moscow"}}, opts)
+	require.ErrorIs(t, err, ErrUnsupportedSymbol)
+
+	opts.Columns = 0
+	_, err = RenderWorldClock(time.Now(), []Clock{{Zone: "UTC"}}, opts)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+func TestIsWorkingTime(t *testing.T) {
+	for _, tc := range []struct {
+		Time    time.Time
+		Working bool
+	}{
+		{Time: time.Date(2022, 5, 2, 9, 0, 0, 0, time.UTC), Working: true},
+		{Time: time.Date(2022, 5, 2, 17, 59, 0, 0, time.UTC), Working: true},
+		{Time: time.Date(2022, 5, 2, 18, 0, 0, 0, time.UTC), Working: false},
+		{Time: time.Date(2022, 5, 2, 8, 59, 0, 0, time.UTC), Working: false},
+		{Time: time.Date(2022, 5, 7, 12, 0, 0, 0, time.UTC), Working: false},
+		{Time: time.Date(2022, 5, 8, 12, 0, 0, 0, time.UTC), Working: false},
+	} {
+		require.Equal(t, tc.Working, isWorkingTime(tc.Time, 9, 18), tc.Time)
+	}
+}
+
+func TestRenderText_Segments(t *testing.T) {
+	on := color.RGBA{R: 255, A: 255}
+	off := color.RGBA{R: 40, A: 255}
+	opts := DefaultOptions(on, 10)
+	opts.Style = StyleSegments7
+
+	// Середина верхнего сегмента: горит у восьмерки, не горит у единицы
+	img, err := RenderText("81", opts)
+	require.NoError(t, err)
+	require.Equal(t, on, img.RGBAAt(15, 3))
+	require.Equal(t, uint8(0), img.RGBAAt(30+10+15, 3).A)
+
+	opts.Segments.OffColor = off
+	img, err = RenderText("81", opts)
+	require.NoError(t, err)
+	require.Equal(t, off, img.RGBAAt(30+10+15, 3))
+	require.Equal(t, on, img.RGBAAt(30+10+27, 25-10))
+
+	// В 16 сегментах верхний сегмент разделен посередине
+	opts.Style = StyleSegments16
+	img, err = RenderText("8", opts)
+	require.NoError(t, err)
+	require.True(t, img.RGBAAt(15, 3).R < on.R)
+	require.Equal(t, on, img.RGBAAt(8, 3))
+
+	// В 14 сегментах ноль перечеркнут
+	count := func(img *image.RGBA) int {
+		lit := 0
+		for i := 0; i < len(img.Pix); i += 4 {
+			if img.Pix[i] == 255 {
+				lit++
+			}
+		}
+		return lit
+	}
+	opts.Segments.OffColor = nil
+	opts.Style = StyleSegments7
+	zero7, err := RenderText("0", opts)
+	require.NoError(t, err)
+	opts.Style = StyleSegments14
+	zero14, err := RenderText("0", opts)
+	require.NoError(t, err)
+	require.Greater(t, count(zero14), count(zero7))
+}
+
+func TestRenderText_SegmentsSlant(t *testing.T) {
+	opts := DefaultOptions(color.Black, 10)
+	opts.Style = StyleSegments7
+	opts.Segments.Slant = 0.2
+
+	img, err := RenderText("1", opts)
+	require.NoError(t, err)
+	rightmost := func(y int) int {
+		for x := img.Rect.Max.X - 1; x >= 0; x-- {
+			if img.RGBAAt(x, y).A > 128 {
+				return x
+			}
+		}
+		return -1
+	}
+	require.Greater(t, rightmost(10), rightmost(40))
+}
+
+func TestRenderText_HideColon(t *testing.T) {
+	c := color.RGBA{B: 255, A: 255}
+	opts := DefaultOptions(c, 2)
+	opts.HideColon = true
+	img, err := RenderText("1:2", opts)
+	require.NoError(t, err)
+	require.Equal(t, color.RGBA{}, img.RGBAAt(4*2+2, 2))
+
+	off := color.RGBA{B: 40, A: 255}
+	opts = DefaultOptions(c, 10)
+	opts.Style = StyleSegments7
+	opts.Segments.OffColor = off
+	img, err = RenderText(":", opts)
+	require.NoError(t, err)
+	require.Equal(t, c, img.RGBAAt(15, 50/3))
+	opts.HideColon = true
+	img, err = RenderText(":", opts)
+	require.NoError(t, err)
+	require.Equal(t, off, img.RGBAAt(15, 50/3))
+}
+
+func TestRenderText_SegmentsErrors(t *testing.T) {
+	opts := DefaultOptions(color.Black, 2)
+	opts.Style = Style(42)
+	_, err := RenderText("1", opts)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+
+	opts = DefaultOptions(color.Black, 2)
+	opts.Style = StyleSegments7
+	opts.Segments.Thickness = 0.9
+	_, err = RenderText("1", opts)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+func TestTimeGIF(t *testing.T) {
+	opts := DefaultOptions(color.RGBA{R: 255, A: 255}, 4)
+	opts.Style = StyleSegments7
+	var b bytes.Buffer
+	require.NoError(t, TimeGIF(&b, parseTime("12:34"), "15:04", opts, 500*time.Millisecond))
+
+	anim, err := gif.DecodeAll(&b)
+	require.NoError(t, err)
+	require.Len(t, anim.Image, 2)
+	require.Equal(t, []int{50, 50}, anim.Delay)
+	require.NotEqual(t, anim.Image[0].Pix, anim.Image[1].Pix)
+}
@@ -0,0 +1,218 @@
+package timepng
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"time"
+)
+
+// Clock описывает одни часы на общей картинке
+type Clock struct {
+	// Label - подпись над часами, например название города
+	Label string
+	// Zone - название часового пояса для time.LoadLocation
+	Zone string
+	// Color - цвет символов, по умолчанию WorldClockOptions.Color
+	Color color.Color
+	// Background - фон ячейки, по умолчанию WorldClockOptions.CellBackground
+	Background color.Color
+}
+
+// WorldClockOptions задает оформление картинки с несколькими часами
+type WorldClockOptions struct {
+	// Layout - формат времени
+	Layout string
+	// Columns - число часов в одной строке
+	Columns int
+	// Scale и LabelScale - масштабы времени и подписи
+	Scale      int
+	LabelScale int
+	// Padding - отступ внутри ячейки в пикселях
+	Padding int
+	// Spacing - промежуток между ячейками и от края картинки в пикселях
+	Spacing int
+	// Color - цвет символов по умолчанию
+	Color color.Color
+	// CellBackground - фон ячеек по умолчанию, Background - фон всей картинки
+	CellBackground color.Color
+	Background     color.Color
+	// WorkStart и WorkEnd - рабочие часы [WorkStart, WorkEnd) по местному времени
+	WorkStart int
+	WorkEnd   int
+	// WorkColor и OffColor - цвет индикатора в рабочее и нерабочее время,
+	// nil отключает индикатор
+	WorkColor color.Color
+	OffColor  color.Color
+}
+
+// DefaultWorldClockOptions возвращает оформление по умолчанию: три часа в строке
+// и индикатор рабочих часов с 9 до 18
+func DefaultWorldClockOptions() WorldClockOptions {
+	return WorldClockOptions{
+		Layout:     "15:04",
+		Columns:    3,
+		Scale:      10,
+		LabelScale: 4,
+		Padding:    10,
+		Spacing:    10,
+		Color:      color.RGBA{R: 100, G: 100, B: 255, A: 255},
+		WorkStart:  9,
+		WorkEnd:    18,
+		WorkColor:  color.RGBA{G: 200, A: 255},
+		OffColor:   color.RGBA{R: 100, G: 100, B: 100, A: 255},
+	}
+}
+
+func (opts WorldClockOptions) validate() error {
+	if opts.Columns < 1 || opts.Scale < 1 || opts.LabelScale < 1 || opts.Padding < 0 || opts.Spacing < 0 {
+		return fmt.Errorf("%w: invalid size", ErrInvalidOptions)
+	}
+	if opts.WorkStart < 0 || opts.WorkEnd > 24 || opts.WorkStart > opts.WorkEnd {
+		return fmt.Errorf("%w: invalid working hours", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// WorldClockPNG записывает в `out` картинку в формате png с часами `clocks`,
+// показывающими время `t` в своих часовых поясах
+func WorldClockPNG(out io.Writer, t time.Time, clocks []Clock, opts WorldClockOptions) error {
+	img, err := RenderWorldClock(t, clocks, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(out, img)
+}
+
+// clockCell - отрисованные части одной ячейки
+type clockCell struct {
+	label   *image.RGBA
+	time    *image.RGBA
+	bg      color.Color
+	working bool
+}
+
+// RenderWorldClock создает изображение с сеткой часов `clocks`, показывающих время `t`.
+// Каждая ячейка содержит подпись, индикатор рабочих часов и время
+func RenderWorldClock(t time.Time, clocks []Clock, opts WorldClockOptions) (*image.RGBA, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if len(clocks) == 0 {
+		return nil, fmt.Errorf("%w: no clocks", ErrInvalidOptions)
+	}
+
+	indicator := 5 * opts.LabelScale
+	content_w, time_h := 0, 0
+	cells := make([]clockCell, len(clocks))
+	for i, clock := range clocks {
+		cell, err := renderClockCell(t, clock, opts)
+		if err != nil {
+			return nil, fmt.Errorf("clock %q: %w", clock.Label, err)
+		}
+		cells[i] = cell
+		if cell.time.Rect.Dx() > content_w {
+			content_w = cell.time.Rect.Dx()
+		}
+		if cell.label != nil && cell.label.Rect.Dx()+opts.Padding+indicator > content_w {
+			content_w = cell.label.Rect.Dx() + opts.Padding + indicator
+		}
+		if cell.time.Rect.Dy() > time_h {
+			time_h = cell.time.Rect.Dy()
+		}
+	}
+
+	if content_w < indicator {
+		content_w = indicator
+	}
+	cell_w := 2*opts.Padding + content_w
+	cell_h := 3*opts.Padding + indicator + time_h
+	cols := opts.Columns
+	if cols > len(clocks) {
+		cols = len(clocks)
+	}
+	rows := (len(clocks) + cols - 1) / cols
+	res_img := image.NewRGBA(image.Rect(0, 0,
+		opts.Spacing+cols*(cell_w+opts.Spacing),
+		opts.Spacing+rows*(cell_h+opts.Spacing)))
+	if opts.Background != nil {
+		draw.Draw(res_img, res_img.Rect, image.NewUniform(opts.Background), image.Point{}, draw.Src)
+	}
+
+	for i, cell := range cells {
+		cell_min := image.Pt(
+			opts.Spacing+i%cols*(cell_w+opts.Spacing),
+			opts.Spacing+i/cols*(cell_h+opts.Spacing))
+		if cell.bg != nil {
+			cell_rect := image.Rectangle{Min: cell_min, Max: cell_min.Add(image.Pt(cell_w, cell_h))}
+			draw.Draw(res_img, cell_rect, image.NewUniform(cell.bg), image.Point{}, draw.Src)
+		}
+		content_min := cell_min.Add(image.Pt(opts.Padding, opts.Padding))
+		if cell.label != nil {
+			drawAt(res_img, cell.label, content_min)
+		}
+		if marker := opts.indicatorColor(cell.working); marker != nil {
+			marker_min := content_min.Add(image.Pt(content_w-indicator, 0))
+			marker_rect := image.Rectangle{Min: marker_min, Max: marker_min.Add(image.Pt(indicator, indicator))}
+			draw.Draw(res_img, marker_rect, image.NewUniform(marker), image.Point{}, draw.Over)
+		}
+		drawAt(res_img, cell.time, content_min.Add(image.Pt(0, indicator+opts.Padding)))
+	}
+	return res_img, nil
+}
+
+func renderClockCell(t time.Time, clock Clock, opts WorldClockOptions) (clockCell, error) {
+	loc, err := time.LoadLocation(clock.Zone)
+	if err != nil {
+		return clockCell{}, err
+	}
+	local := t.In(loc)
+	c := clock.Color
+	if c == nil {
+		c = opts.Color
+	}
+
+	cell := clockCell{
+		bg:      clock.Background,
+		working: isWorkingTime(local, opts.WorkStart, opts.WorkEnd),
+	}
+	if cell.bg == nil {
+		cell.bg = opts.CellBackground
+	}
+	if clock.Label != "" {
+		cell.label, err = renderGlyphs(clock.Label, DefaultOptions(c, opts.LabelScale), labelGlyph)
+		if err != nil {
+			return clockCell{}, err
+		}
+	}
+	cell.time, err = RenderTime(local, opts.Layout, DefaultOptions(c, opts.Scale))
+	if err != nil {
+		return clockCell{}, err
+	}
+	return cell, nil
+}
+
+func (opts WorldClockOptions) indicatorColor(working bool) color.Color {
+	if working {
+		return opts.WorkColor
+	}
+	return opts.OffColor
+}
+
+// isWorkingTime проверяет, что местное время `local` приходится на будний день
+// и попадает в рабочие часы [start, end)
+func isWorkingTime(local time.Time, start, end int) bool {
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return false
+	}
+	return start <= local.Hour() && local.Hour() < end
+}
+
+// drawAt накладывает `src` на `dst` так, чтобы левый верхний угол оказался в `at`
+func drawAt(dst *image.RGBA, src *image.RGBA, at image.Point) {
+	r := image.Rectangle{Min: at, Max: at.Add(src.Rect.Size())}
+	draw.Draw(dst, r, src, src.Rect.Min, draw.Over)
+}
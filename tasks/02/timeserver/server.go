@@ -0,0 +1,213 @@
+package timeserver
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/02/timepng"
+)
+
+const (
+	defaultLayout = "15:04"
+	defaultScale  = 10
+	maxScale      = 64
+	// Ширина картинки растет с длиной формата, поэтому он тоже ограничен
+	maxLayoutLen = 32
+)
+
+var defaultColor = color.RGBA{R: 100, G: 100, B: 255, A: 255}
+
+var (
+	ErrInvalidColor  = errors.New("invalid color")
+	ErrInvalidScale  = errors.New("invalid scale")
+	ErrInvalidTZ     = errors.New("invalid time zone")
+	ErrInvalidLayout = errors.New("invalid layout")
+)
+
+// Server отдает картинки с текущим временем и кэширует их
+type Server struct {
+	cache *lruCache
+	now   func() time.Time
+}
+
+func NewServer(cacheSize int) *Server {
+	return &Server{
+		cache: newLRUCache(cacheSize),
+		now:   time.Now,
+	}
+}
+
+type params struct {
+	loc    *time.Location
+	tz     string
+	color  color.RGBA
+	bg     color.RGBA
+	scale  int
+	layout string
+}
+
+// key однозначно задает картинку для параметров `p` и усеченного времени `t`
+func (p params) key(t time.Time) string {
+	return fmt.Sprintf("%s|%s|%s|%d|%s|%d",
+		p.tz, formatColor(p.color), formatColor(p.bg), p.scale, p.layout, t.Unix())
+}
+
+// HandleTime обрабатывает запросы вида
+// `/time.png?tz=Europe/Moscow&color=ff0000&scale=8&fmt=15:04:05&bg=transparent`
+func (s *Server) HandleTime(rw http.ResponseWriter, req *http.Request) {
+	p, err := parseParams(req)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := s.now().In(p.loc)
+	step := granularity(p.layout)
+	truncated := now.Truncate(step)
+	expires := truncated.Add(step)
+	key := p.key(truncated)
+	etag := makeETag(key)
+
+	header := rw.Header()
+	header.Set("ETag", etag)
+	header.Set("Last-Modified", truncated.UTC().Format(http.TimeFormat))
+	header.Set("Expires", expires.UTC().Format(http.TimeFormat))
+	header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(expires.Sub(now)/time.Second)))
+
+	if notModified(req, etag, truncated) {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, ok := s.cache.Get(key)
+	if !ok {
+		data, err = render(truncated, p)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.cache.Put(key, data)
+	}
+
+	header.Set("Content-Type", "image/png")
+	header.Set("Content-Length", strconv.Itoa(len(data)))
+	rw.WriteHeader(http.StatusOK)
+	_, _ = rw.Write(data)
+}
+
+func render(t time.Time, p params) ([]byte, error) {
+	opts := timepng.DefaultOptions(p.color, p.scale)
+	if p.bg.A != 0 {
+		opts.Background = p.bg
+	}
+	var b bytes.Buffer
+	if err := timepng.TimePNGWithOptions(&b, t, p.layout, opts); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func parseParams(req *http.Request) (params, error) {
+	query := req.URL.Query()
+	p := params{
+		tz:     query.Get("tz"),
+		color:  defaultColor,
+		scale:  defaultScale,
+		layout: defaultLayout,
+	}
+
+	loc, err := time.LoadLocation(p.tz)
+	if err != nil {
+		return params{}, fmt.Errorf("%w: %q", ErrInvalidTZ, p.tz)
+	}
+	p.loc = loc
+
+	if c := query.Get("color"); c != "" {
+		if p.color, err = parseColor(c); err != nil {
+			return params{}, err
+		}
+	}
+	if bg := query.Get("bg"); bg != "" && bg != "transparent" {
+		if p.bg, err = parseColor(bg); err != nil {
+			return params{}, err
+		}
+	}
+	if sc := query.Get("scale"); sc != "" {
+		p.scale, err = strconv.Atoi(sc)
+		if err != nil || p.scale < 1 || p.scale > maxScale {
+			return params{}, fmt.Errorf("%w: %q", ErrInvalidScale, sc)
+		}
+	}
+	if layout := query.Get("fmt"); layout != "" {
+		if len(layout) > maxLayoutLen {
+			return params{}, fmt.Errorf("%w: longer than %d bytes", ErrInvalidLayout, maxLayoutLen)
+		}
+		// Пробная отрисовка в масштабе 1 находит неподдерживаемые символы до проверки
+		// условных заголовков, иначе на неверный запрос мог бы уйти 304
+		if _, err := timepng.RenderTime(time.Time{}, layout, timepng.DefaultOptions(p.color, 1)); err != nil {
+			return params{}, fmt.Errorf("%w: %v", ErrInvalidLayout, err)
+		}
+		p.layout = layout
+	}
+	return p, nil
+}
+
+// parseColor разбирает цвет вида `rrggbb` или `rrggbbaa`, допускается ведущий `#`
+func parseColor(s string) (color.RGBA, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil || (len(raw) != 3 && len(raw) != 4) {
+		return color.RGBA{}, fmt.Errorf("%w: %q", ErrInvalidColor, s)
+	}
+	c := color.RGBA{R: raw[0], G: raw[1], B: raw[2], A: 255}
+	if len(raw) == 4 {
+		c.A = raw[3]
+	}
+	return c, nil
+}
+
+func formatColor(c color.RGBA) string {
+	return hex.EncodeToString([]byte{c.R, c.G, c.B, c.A})
+}
+
+// granularity возвращает, как часто меняется картинка для формата `layout`:
+// раз в секунду, если в формате есть секунды, иначе раз в минуту
+func granularity(layout string) time.Duration {
+	ref := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if ref.Format(layout) != ref.Add(time.Second+100*time.Millisecond).Format(layout) {
+		return time.Second
+	}
+	return time.Minute
+}
+
+func makeETag(key string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// notModified проверяет условные заголовки запроса. `If-None-Match`
+// имеет приоритет над `If-Modified-Since`
+func notModified(req *http.Request, etag string, modified time.Time) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := req.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		return err == nil && !modified.Truncate(time.Second).After(since)
+	}
+	return false
+}
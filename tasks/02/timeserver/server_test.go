@@ -0,0 +1,150 @@
+package timeserver
+
+import (
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, now time.Time) (*Server, *httptest.Server) {
+	srv := NewServer(2)
+	srv.now = func() time.Time {
+		return now
+	}
+	r := chi.NewMux()
+	r.Get("/time.png", srv.HandleTime)
+	s := httptest.NewServer(r)
+	t.Cleanup(s.Close)
+	return srv, s
+}
+
+func get(t *testing.T, u string, header http.Header) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	require.NoError(t, err)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = resp.Body.Close()
+	})
+	return resp
+}
+
+func TestHandleTime(t *testing.T) {
+	now := time.Date(2022, 5, 1, 10, 15, 20, 0, time.UTC)
+	_, s := newTestServer(t, now)
+
+	resp := get(t, s.URL+"/time.png?tz=Europe/Moscow&color=ff0000&scale=2&bg=00ff00", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	require.Equal(t, "public, max-age=40", resp.Header.Get("Cache-Control"))
+	require.Equal(t, "Sun, 01 May 2022 10:16:00 GMT", resp.Header.Get("Expires"))
+	require.Equal(t, "Sun, 01 May 2022 10:15:00 GMT", resp.Header.Get("Last-Modified"))
+	require.NotEmpty(t, resp.Header.Get("ETag"))
+
+	img, err := png.Decode(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, 19*2, img.Bounds().Dx())
+	require.Equal(t, 5*2, img.Bounds().Dy())
+	// "13:15": левый верхний угол единицы пустой, угол тройки закрашен
+	require.Equal(t, color.RGBA{G: 255, A: 255}, color.RGBAModel.Convert(img.At(0, 0)))
+	require.Equal(t, color.RGBA{R: 255, A: 255}, color.RGBAModel.Convert(img.At(4*2, 0)))
+}
+
+func TestHandleTime_Seconds(t *testing.T) {
+	now := time.Date(2022, 5, 1, 10, 15, 20, 500, time.UTC)
+	_, s := newTestServer(t, now)
+
+	resp := get(t, s.URL+"/time.png?tz=UTC&fmt=15:04:05", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "Sun, 01 May 2022 10:15:21 GMT", resp.Header.Get("Expires"))
+	img, err := png.Decode(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, 31*defaultScale, img.Bounds().Dx())
+}
+
+func TestHandleTime_NotModified(t *testing.T) {
+	now := time.Date(2022, 5, 1, 10, 15, 20, 0, time.UTC)
+	_, s := newTestServer(t, now)
+	u := s.URL + "/time.png?tz=UTC"
+
+	resp := get(t, u, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+
+	resp = get(t, u, http.Header{"If-None-Match": {etag}})
+	require.Equal(t, http.StatusNotModified, resp.StatusCode)
+	require.Equal(t, etag, resp.Header.Get("ETag"))
+
+	resp = get(t, u, http.Header{"If-None-Match": {`"other"`}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = get(t, u, http.Header{"If-Modified-Since": {"Sun, 01 May 2022 10:15:00 GMT"}})
+	require.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp = get(t, u, http.Header{"If-Modified-Since": {"Sun, 01 May 2022 10:14:59 GMT"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestHandleTime_Cache(t *testing.T) {
+	now := time.Date(2022, 5, 1, 10, 15, 20, 0, time.UTC)
+	srv, s := newTestServer(t, now)
+
+	for _, u := range []string{"?scale=1", "?scale=2", "?scale=1", "?scale=3"} {
+		resp := get(t, s.URL+"/time.png"+u, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	require.Equal(t, 2, srv.cache.Len())
+	_, ok := srv.cache.Get(params{tz: "", color: defaultColor, scale: 1, layout: defaultLayout}.key(now.Truncate(time.Minute)))
+	require.True(t, ok)
+	_, ok = srv.cache.Get(params{tz: "", color: defaultColor, scale: 2, layout: defaultLayout}.key(now.Truncate(time.Minute)))
+	require.False(t, ok)
+}
+
+func TestHandleTime_BadRequest(t *testing.T) {
+	_, s := newTestServer(t, time.Now())
+	for _, q := range []string{
+		"?tz=Mars/Olympus",
+		"?color=red",
+		"?bg=12345",
+		"?scale=0",
+		"?scale=1000",
+		"?fmt=Mon",
+		"?scale=64&fmt=" + strings.Repeat("15:04:05", 1000),
+	} {
+		resp := get(t, s.URL+"/time.png"+q, nil)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, q)
+	}
+
+	// Неверные параметры важнее условных заголовков
+	resp := get(t, s.URL+"/time.png?fmt=Mon", http.Header{"If-None-Match": {"*"}})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	req := httptest.NewRequest(http.MethodGet, "/time.png?fmt="+strings.Repeat("1", maxLayoutLen+1), nil)
+	_, err := parseParams(req)
+	require.ErrorIs(t, err, ErrInvalidLayout)
+}
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache(2)
+	c.Put("a", []byte("1"))
+	c.Put("b", []byte("2"))
+	_, ok := c.Get("a")
+	require.True(t, ok)
+	c.Put("c", []byte("3"))
+	_, ok = c.Get("b")
+	require.False(t, ok)
+	v, ok := c.Get("a")
+	require.True(t, ok)
+	require.Equal(t, []byte("1"), v)
+	require.Equal(t, 2, c.Len())
+}
@@ -0,0 +1,292 @@
+// Package authserver выдает access и refresh токены с ротацией refresh токенов
+package authserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+const (
+	defaultAccessTTL  = 15 * time.Minute
+	defaultRefreshTTL = 30 * 24 * time.Hour
+
+	// Получатель refresh токенов: они не должны приниматься вместо access токенов
+	refreshAudience = "refresh"
+	tokenIDSize     = 16
+)
+
+// Config - параметры сервера
+type Config struct {
+	// CheckPassword проверяет логин и пароль и возвращает идентификатор пользователя
+	CheckPassword func(username, password string) (subject string, ok bool)
+	// SignOptions задают метод и ключ подписи токенов
+	SignOptions []jwt.Option
+	Issuer      string
+	Audience    string
+	AccessTTL   time.Duration
+	RefreshTTL  time.Duration
+	// Revoker хранит отозванные access токены, по умолчанию - jwt.MemoryRevoker
+	Revoker jwt.Revoker
+	// Logger получает причины отказов, клиенту они не сообщаются. По умолчанию - log.Default()
+	Logger *log.Logger
+}
+
+// Server обрабатывает запросы `/token/login`, `/token/refresh` и `/token/revoke`
+type Server struct {
+	cfg   Config
+	store Store
+	now   func() time.Time
+}
+
+func NewServer(cfg Config, store Store) *Server {
+	if cfg.AccessTTL == 0 {
+		cfg.AccessTTL = defaultAccessTTL
+	}
+	if cfg.RefreshTTL == 0 {
+		cfg.RefreshTTL = defaultRefreshTTL
+	}
+	if cfg.Revoker == nil {
+		cfg.Revoker = jwt.NewMemoryRevoker()
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Default()
+	}
+	return &Server{cfg: cfg, store: store, now: time.Now}
+}
+
+// Revoker возвращает хранилище отозванных access токенов для проверки через jwt.WithRevoker
+func (s *Server) Revoker() jwt.Revoker {
+	return s.cfg.Revoker
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type errorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// refreshData - пользовательские данные refresh токена
+type refreshData struct {
+	Family string `json:"fam"`
+}
+
+// HandleLogin выдает пару токенов по параметрам `username` и `password`
+func (s *Server) HandleLogin(rw http.ResponseWriter, req *http.Request) {
+	username, password := req.PostFormValue("username"), req.PostFormValue("password")
+	if username == "" || password == "" {
+		writeError(rw, http.StatusBadRequest, "invalid_request", "username and password are required")
+		return
+	}
+	subject, ok := s.cfg.CheckPassword(username, password)
+	if !ok {
+		writeError(rw, http.StatusUnauthorized, "invalid_grant", "invalid username or password")
+		return
+	}
+	family, err := newTokenID()
+	if err != nil {
+		writeError(rw, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	s.issue(rw, req, subject, family)
+}
+
+// HandleRefresh обменивает refresh токен из параметра `refresh_token` на новую пару.
+// Повторное использование refresh токена отзывает все семейство вместе с выданными
+// в нем access токенами: одна из сторон, у которых есть токен, - злоумышленник
+func (s *Server) HandleRefresh(rw http.ResponseWriter, req *http.Request) {
+	token := req.PostFormValue("refresh_token")
+	if token == "" {
+		writeError(rw, http.StatusBadRequest, "invalid_request", "refresh_token is required")
+		return
+	}
+	var data refreshData
+	var claims jwt.Claims
+	if err := s.decodeRefresh(token, &data, &claims); err != nil {
+		s.cfg.Logger.Printf("[WARN] Refresh token rejected: %v", err)
+		writeError(rw, http.StatusUnauthorized, "invalid_grant", "invalid refresh token")
+		return
+	}
+
+	record, err := s.store.Use(req.Context(), claims.ID)
+	switch {
+	case errors.Is(err, ErrRefreshReused):
+		s.cfg.Logger.Printf("[WARN] Refresh token reused, revoking family %s: %v", record.Family, err)
+		if err := s.revokeFamily(req.Context(), record.Family); err != nil {
+			s.cfg.Logger.Printf("[ERROR] Revoke family %s: %v", record.Family, err)
+			writeError(rw, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+		writeError(rw, http.StatusUnauthorized, "invalid_grant", "refresh token reused")
+		return
+	case errors.Is(err, ErrRefreshNotFound):
+		s.cfg.Logger.Printf("[WARN] Refresh token rejected: %v", err)
+		writeError(rw, http.StatusUnauthorized, "invalid_grant", "invalid refresh token")
+		return
+	case errors.Is(err, ErrFamilyRevoked):
+		s.cfg.Logger.Printf("[WARN] Refresh token rejected: %v", err)
+		writeError(rw, http.StatusUnauthorized, "invalid_grant", "refresh token revoked")
+		return
+	case err != nil:
+		s.cfg.Logger.Printf("[ERROR] Use refresh token: %v", err)
+		writeError(rw, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	s.issue(rw, req, record.Subject, record.Family)
+}
+
+// HandleRevoke отзывает токен из параметра `token` (RFC 7009). Для refresh токена
+// отзывается все семейство. Недействительные токены игнорируются
+func (s *Server) HandleRevoke(rw http.ResponseWriter, req *http.Request) {
+	token := req.PostFormValue("token")
+	if token == "" {
+		writeError(rw, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+
+	var err error
+	var data refreshData
+	var claims jwt.Claims
+	if s.decodeRefresh(token, &data, &claims) == nil {
+		err = s.revokeFamily(req.Context(), data.Family)
+	} else if s.decodeAccess(token, &claims) == nil {
+		err = jwt.RevokeClaims(s.cfg.Revoker, claims)
+	}
+	if err != nil {
+		writeError(rw, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// revokeFamily отзывает семейство refresh токенов и access токены, выданные вместе с ними
+func (s *Server) revokeFamily(ctx context.Context, family string) error {
+	tokens, err := s.store.RevokeFamily(ctx, family)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	for _, token := range tokens {
+		if token.AccessID == "" || !now.Before(token.AccessExpiresAt) {
+			continue
+		}
+		if err := s.cfg.Revoker.Revoke(token.AccessID, token.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// issue выдает новую пару токенов в семействе `family`
+func (s *Server) issue(rw http.ResponseWriter, req *http.Request, subject, family string) {
+	accessID, err := newTokenID()
+	if err != nil {
+		writeError(rw, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	refreshID, err := newTokenID()
+	if err != nil {
+		writeError(rw, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	now := s.now()
+	accessExpires := now.Add(s.cfg.AccessTTL)
+	access, err := jwt.Encode(struct{}{}, s.tokenOptions(subject, accessID, s.cfg.Audience, accessExpires)...)
+	if err != nil {
+		writeError(rw, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	refreshExpires := now.Add(s.cfg.RefreshTTL)
+	refresh, err := jwt.Encode(refreshData{Family: family}, s.tokenOptions(subject, refreshID, refreshAudience, refreshExpires)...)
+	if err != nil {
+		writeError(rw, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	record := RefreshToken{
+		ID:              refreshID,
+		Family:          family,
+		Subject:         subject,
+		ExpiresAt:       refreshExpires,
+		AccessID:        accessID,
+		AccessExpiresAt: accessExpires,
+	}
+	if err := s.store.Create(req.Context(), record); err != nil {
+		writeError(rw, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	rw.Header().Set("Cache-Control", "no-store")
+	writeJSON(rw, http.StatusOK, tokenResponse{
+		AccessToken:  string(access),
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.cfg.AccessTTL / time.Second),
+		RefreshToken: string(refresh),
+	})
+}
+
+func (s *Server) tokenOptions(subject, id, audience string, expires time.Time) []jwt.Option {
+	opts := append(s.cfg.SignOptions[:len(s.cfg.SignOptions):len(s.cfg.SignOptions)],
+		jwt.WithSubject(subject), jwt.WithID(id), jwt.WithIssuer(s.cfg.Issuer),
+		jwt.WithIssuedAt(), jwt.WithExpires(expires))
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+	return opts
+}
+
+func (s *Server) decodeOptions(audience string, claims *jwt.Claims) []jwt.Option {
+	opts := append(s.cfg.SignOptions[:len(s.cfg.SignOptions):len(s.cfg.SignOptions)],
+		jwt.WithIssuer(s.cfg.Issuer), jwt.WithClaims(claims))
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+	return opts
+}
+
+func (s *Server) decodeRefresh(token string, data *refreshData, claims *jwt.Claims) error {
+	return jwt.Decode([]byte(token), data, s.decodeOptions(refreshAudience, claims)...)
+}
+
+func (s *Server) decodeAccess(token string, claims *jwt.Claims) error {
+	var data struct{}
+	opts := append(s.decodeOptions(s.cfg.Audience, claims), jwt.WithRevoker(s.cfg.Revoker))
+	if err := jwt.Decode([]byte(token), &data, opts...); err != nil {
+		return err
+	}
+	if claims.Audience.Contains(refreshAudience) {
+		return jwt.ErrInvalidAudience
+	}
+	return nil
+}
+
+func newTokenID() (string, error) {
+	id := make([]byte, tokenIDSize)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func writeError(rw http.ResponseWriter, code int, reason, description string) {
+	writeJSON(rw, code, errorResponse{Error: reason, Description: description})
+}
+
+func writeJSON(rw http.ResponseWriter, code int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	_ = json.NewEncoder(rw).Encode(v)
+}
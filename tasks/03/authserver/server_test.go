@@ -0,0 +1,239 @@
+package authserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+var signOptions = []jwt.Option{jwt.WithSignMethod(jwt.HS256), jwt.WithKey([]byte("secret"))}
+
+func newTestServer() (*Server, http.Handler) {
+	srv := NewServer(Config{
+		CheckPassword: func(username, password string) (string, bool) {
+			return "user-" + username, username == "alice" && password == "pa$$"
+		},
+		SignOptions: signOptions,
+		Issuer:      "auth",
+		Audience:    "api",
+	}, NewMemoryStore())
+
+	r := chi.NewMux()
+	r.Post("/token/login", srv.HandleLogin)
+	r.Post("/token/refresh", srv.HandleRefresh)
+	r.Post("/token/revoke", srv.HandleRevoke)
+	return srv, r
+}
+
+func post(t *testing.T, h http.Handler, path string, form url.Values) (int, tokenResponse, errorResponse) {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var tokens tokenResponse
+	var errResp errorResponse
+	if rec.Code == http.StatusOK && rec.Body.Len() != 0 {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+	} else if rec.Code != http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	}
+	return rec.Code, tokens, errResp
+}
+
+func login(t *testing.T, h http.Handler) tokenResponse {
+	code, tokens, _ := post(t, h, "/token/login", url.Values{"username": {"alice"}, "password": {"pa$$"}})
+	require.Equal(t, http.StatusOK, code)
+	return tokens
+}
+
+func refresh(t *testing.T, h http.Handler, token string) (int, tokenResponse) {
+	code, tokens, _ := post(t, h, "/token/refresh", url.Values{"refresh_token": {token}})
+	return code, tokens
+}
+
+func TestLogin(t *testing.T) {
+	srv, h := newTestServer()
+	tokens := login(t, h)
+	require.Equal(t, "Bearer", tokens.TokenType)
+	require.Equal(t, int64(defaultAccessTTL/time.Second), tokens.ExpiresIn)
+
+	var claims jwt.Claims
+	var data struct{}
+	require.NoError(t, jwt.Decode([]byte(tokens.AccessToken), &data, append(signOptions,
+		jwt.WithAudience("api"), jwt.WithIssuer("auth"), jwt.WithRevoker(srv.Revoker()), jwt.WithClaims(&claims))...))
+	require.Equal(t, "user-alice", claims.Subject)
+
+	// Refresh токен не подходит вместо access токена
+	err := jwt.Decode([]byte(tokens.RefreshToken), &data, append(signOptions, jwt.WithAudience("api"))...)
+	require.ErrorIs(t, err, jwt.ErrInvalidAudience)
+
+	code, _, errResp := post(t, h, "/token/login", url.Values{"username": {"alice"}, "password": {"wrong"}})
+	require.Equal(t, http.StatusUnauthorized, code)
+	require.Equal(t, "invalid_grant", errResp.Error)
+
+	code, _, errResp = post(t, h, "/token/login", url.Values{"username": {"alice"}})
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, "invalid_request", errResp.Error)
+}
+
+func TestRefresh_Rotation(t *testing.T) {
+	_, h := newTestServer()
+	first := login(t, h)
+
+	code, second := refresh(t, h, first.RefreshToken)
+	require.Equal(t, http.StatusOK, code)
+	require.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	code, third := refresh(t, h, second.RefreshToken)
+	require.Equal(t, http.StatusOK, code)
+
+	// Повторное использование отзывает все семейство, включая последний токен
+	code, _ = refresh(t, h, first.RefreshToken)
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, _ = refresh(t, h, third.RefreshToken)
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	// Другие семейства не затрагиваются
+	other := login(t, h)
+	code, _ = refresh(t, h, other.RefreshToken)
+	require.Equal(t, http.StatusOK, code)
+
+	// Access токен не подходит вместо refresh токена
+	code, _ = refresh(t, h, other.AccessToken)
+	require.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestRefresh_ReuseRevokesAccess(t *testing.T) {
+	srv, h := newTestServer()
+	decodeAccess := func(token string) error {
+		var data struct{}
+		return jwt.Decode([]byte(token), &data, append(signOptions, jwt.WithRevoker(srv.Revoker()))...)
+	}
+
+	// Злоумышленник украл пару и обновил ее раньше пользователя
+	stolen := login(t, h)
+	code, attacker := refresh(t, h, stolen.RefreshToken)
+	require.Equal(t, http.StatusOK, code)
+	require.NoError(t, decodeAccess(attacker.AccessToken))
+
+	// Пользователь повторно использует refresh токен: отзываются access токены всего семейства
+	code, _ = refresh(t, h, stolen.RefreshToken)
+	require.Equal(t, http.StatusUnauthorized, code)
+	require.ErrorIs(t, decodeAccess(stolen.AccessToken), jwt.ErrTokenRevoked)
+	require.ErrorIs(t, decodeAccess(attacker.AccessToken), jwt.ErrTokenRevoked)
+
+	// Другие семейства не затрагиваются
+	other := login(t, h)
+	require.NoError(t, decodeAccess(other.AccessToken))
+}
+
+func TestRefresh_ErrorDescription(t *testing.T) {
+	srv, h := newTestServer()
+	var logs bytes.Buffer
+	srv.cfg.Logger = log.New(&logs, "", 0)
+
+	refreshError := func(token string) errorResponse {
+		code, _, errResp := post(t, h, "/token/refresh", url.Values{"refresh_token": {token}})
+		require.Equal(t, http.StatusUnauthorized, code)
+		require.Equal(t, "invalid_grant", errResp.Error)
+		return errResp
+	}
+
+	first := login(t, h)
+	require.Equal(t, "invalid refresh token", refreshError(first.AccessToken).Description)
+
+	code, second := refresh(t, h, first.RefreshToken)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "refresh token reused", refreshError(first.RefreshToken).Description)
+	require.Equal(t, "refresh token revoked", refreshError(second.RefreshToken).Description)
+
+	// Подробности пишутся только в лог сервера
+	require.Contains(t, logs.String(), jwt.ErrInvalidAudience.Error())
+	require.Contains(t, logs.String(), ErrRefreshReused.Error())
+	require.Contains(t, logs.String(), ErrFamilyRevoked.Error())
+}
+
+func TestRefresh_Concurrent(t *testing.T) {
+	_, h := newTestServer()
+	tokens := login(t, h)
+
+	const n = 10
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i], _ = refresh(t, h, tokens.RefreshToken)
+		}(i)
+	}
+	wg.Wait()
+
+	var ok int
+	for _, code := range codes {
+		if code == http.StatusOK {
+			ok++
+		}
+	}
+	require.Equal(t, 1, ok)
+}
+
+func TestRevoke(t *testing.T) {
+	srv, h := newTestServer()
+	tokens := login(t, h)
+
+	code, _, _ := post(t, h, "/token/revoke", url.Values{"token": {tokens.AccessToken}})
+	require.Equal(t, http.StatusOK, code)
+	var data struct{}
+	err := jwt.Decode([]byte(tokens.AccessToken), &data, append(signOptions, jwt.WithRevoker(srv.Revoker()))...)
+	require.ErrorIs(t, err, jwt.ErrTokenRevoked)
+
+	code, _, _ = post(t, h, "/token/revoke", url.Values{"token": {tokens.RefreshToken}})
+	require.Equal(t, http.StatusOK, code)
+	code, _ = refresh(t, h, tokens.RefreshToken)
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	// Отзыв refresh токена отзывает и access токен семейства
+	tokens = login(t, h)
+	code, _, _ = post(t, h, "/token/revoke", url.Values{"token": {tokens.RefreshToken}})
+	require.Equal(t, http.StatusOK, code)
+	err = jwt.Decode([]byte(tokens.AccessToken), &data, append(signOptions, jwt.WithRevoker(srv.Revoker()))...)
+	require.ErrorIs(t, err, jwt.ErrTokenRevoked)
+
+	code, _, _ = post(t, h, "/token/revoke", url.Values{"token": {"garbage"}})
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestMemoryStore_Evict(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	require.NoError(t, s.Create(context.Background(), RefreshToken{ID: "a", Family: "f", ExpiresAt: now.Add(time.Minute)}))
+	revoked, err := s.RevokeFamily(context.Background(), "f")
+	require.NoError(t, err)
+	require.Len(t, revoked, 1)
+	_, err = s.Use(context.Background(), "a")
+	require.ErrorIs(t, err, ErrFamilyRevoked)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, s.Create(context.Background(), RefreshToken{ID: "b", Family: "g", ExpiresAt: now.Add(time.Minute)}))
+	require.Len(t, s.tokens, 1)
+	require.Empty(t, s.families)
+	_, err = s.Use(context.Background(), "a")
+	require.ErrorIs(t, err, ErrRefreshNotFound)
+}
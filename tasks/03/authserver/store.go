@@ -0,0 +1,118 @@
+package authserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRefreshNotFound = errors.New("refresh token not found")
+	ErrRefreshReused   = errors.New("refresh token reused")
+	ErrFamilyRevoked   = errors.New("token family revoked")
+)
+
+// RefreshToken - запись о выданном refresh токене. Все токены, полученные
+// ротацией из одного входа, образуют семейство
+type RefreshToken struct {
+	ID        string
+	Family    string
+	Subject   string
+	ExpiresAt time.Time
+	// Access токен, выданный вместе с этим refresh токеном
+	AccessID        string
+	AccessExpiresAt time.Time
+}
+
+// Store хранит выданные refresh токены
+type Store interface {
+	// Create сохраняет новый токен
+	Create(ctx context.Context, token RefreshToken) error
+	// Use помечает токен использованным. Повторное использование возвращает
+	// ErrRefreshReused вместе с записью, токен отозванного семейства - ErrFamilyRevoked
+	Use(ctx context.Context, id string) (RefreshToken, error)
+	// RevokeFamily отзывает все токены семейства и возвращает их, чтобы можно было
+	// отозвать выданные вместе с ними access токены
+	RevokeFamily(ctx context.Context, family string) ([]RefreshToken, error)
+}
+
+type memoryRecord struct {
+	token RefreshToken
+	used  bool
+}
+
+// MemoryStore хранит токены в памяти, истекшие записи удаляются при создании новых
+type MemoryStore struct {
+	mutex    sync.Mutex
+	tokens   map[string]*memoryRecord
+	families map[string]time.Time
+	now      func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tokens:   make(map[string]*memoryRecord),
+		families: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryStore) Create(_ context.Context, token RefreshToken) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.evict()
+	s.tokens[token.ID] = &memoryRecord{token: token}
+	return nil
+}
+
+func (s *MemoryStore) Use(_ context.Context, id string) (RefreshToken, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	record, ok := s.tokens[id]
+	if !ok || !s.now().Before(record.token.ExpiresAt) {
+		return RefreshToken{}, ErrRefreshNotFound
+	}
+	if _, revoked := s.families[record.token.Family]; revoked {
+		return record.token, ErrFamilyRevoked
+	}
+	if record.used {
+		return record.token, ErrRefreshReused
+	}
+	record.used = true
+	return record.token, nil
+}
+
+func (s *MemoryStore) RevokeFamily(_ context.Context, family string) ([]RefreshToken, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// Запись о семействе нужна, пока не истекут все его токены
+	var expires time.Time
+	var tokens []RefreshToken
+	for _, record := range s.tokens {
+		if record.token.Family != family {
+			continue
+		}
+		tokens = append(tokens, record.token)
+		if record.token.ExpiresAt.After(expires) {
+			expires = record.token.ExpiresAt
+		}
+	}
+	s.families[family] = expires
+	return tokens, nil
+}
+
+// evict удаляет истекшие токены и семейства
+func (s *MemoryStore) evict() {
+	now := s.now()
+	for id, record := range s.tokens {
+		if !now.Before(record.token.ExpiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+	for family, expires := range s.families {
+		if !now.Before(expires) {
+			delete(s.families, family)
+		}
+	}
+}
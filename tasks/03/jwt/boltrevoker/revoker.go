@@ -0,0 +1,112 @@
+// Package boltrevoker хранит отозванные JWT токены в bbolt
+package boltrevoker
+
+import (
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+var bucket = []byte("revoked")
+
+var _ jwt.Revoker = (*Revoker)(nil)
+
+// Revoker хранит идентификаторы отозванных токенов в базе bbolt. Значение записи -
+// время истечения токена в секундах (0 для бессрочных токенов). Запись действует
+// еще jwt.RevokerConfig.Grace после истечения токена
+type Revoker struct {
+	db    *bolt.DB
+	grace time.Duration
+}
+
+// New создает хранилище в базе `db`
+func New(db *bolt.DB, opts ...jwt.RevokerOption) (*Revoker, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Revoker{db: db, grace: jwt.NewRevokerConfig(opts...).Grace}, nil
+}
+
+func (r *Revoker) Revoke(jti string, expires time.Time) error {
+	value := make([]byte, 8)
+	if !expires.IsZero() {
+		binary.BigEndian.PutUint64(value, uint64(expires.Unix()))
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(jti), value)
+	})
+}
+
+func (r *Revoker) IsRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := r.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(bucket).Get([]byte(jti))
+		revoked = value != nil && !r.expired(value, timeFunc())
+		return nil
+	})
+	return revoked, err
+}
+
+// Purge удаляет записи об истекших токенах и возвращает их количество
+func (r *Revoker) Purge() (int, error) {
+	var purged int
+	now := timeFunc()
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		var keys [][]byte
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if r.expired(v, now) {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			if err := tx.Bucket(bucket).Delete(k); err != nil {
+				return err
+			}
+		}
+		purged = len(keys)
+		return nil
+	})
+	return purged, err
+}
+
+// StartPurge периодически удаляет истекшие записи, пока не будет вызвана возвращенная функция
+func (r *Revoker) StartPurge(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_, _ = r.Purge()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (r *Revoker) expired(value []byte, now time.Time) bool {
+	if len(value) != 8 {
+		return false
+	}
+	exp := binary.BigEndian.Uint64(value)
+	return exp != 0 && !now.Before(time.Unix(int64(exp), 0).Add(r.grace))
+}
+
+// To mock time in tests
+var timeFunc = time.Now
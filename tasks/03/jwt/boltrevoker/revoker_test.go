@@ -0,0 +1,113 @@
+package boltrevoker
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/goleak"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+func openRevoker(t *testing.T) *Revoker {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "revoked.db"), 0o600, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+	r, err := New(db)
+	require.NoError(t, err)
+	return r
+}
+
+func TestRevoker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	r := openRevoker(t)
+	require.NoError(t, r.Revoke("a", now.Add(time.Minute)))
+	require.NoError(t, r.Revoke("b", time.Time{}))
+
+	for _, jti := range []string{"a", "b"} {
+		revoked, err := r.IsRevoked(jti)
+		require.NoError(t, err)
+		require.True(t, revoked, jti)
+	}
+	revoked, err := r.IsRevoked("c")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// Запись действует еще jwt.DefaultRevocationGrace после истечения токена
+	now = now.Add(time.Minute)
+	revoked, err = r.IsRevoked("a")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	now = now.Add(jwt.DefaultRevocationGrace)
+	revoked, err = r.IsRevoked("a")
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	purged, err := r.Purge()
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+	revoked, err = r.IsRevoked("b")
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestRevoker_Decode(t *testing.T) {
+	r := openRevoker(t)
+	opts := []jwt.Option{jwt.WithSignMethod(jwt.HS256), jwt.WithKey([]byte("secret")), jwt.WithRevoker(r)}
+
+	token, err := jwt.Encode("hello", append(opts, jwt.WithID("1"), jwt.WithTTL(time.Hour))...)
+	require.NoError(t, err)
+
+	var data string
+	var claims jwt.Claims
+	require.NoError(t, jwt.Decode(token, &data, append(opts, jwt.WithClaims(&claims))...))
+	require.NoError(t, jwt.RevokeClaims(r, claims))
+	require.ErrorIs(t, jwt.Decode(token, &data, opts...), jwt.ErrTokenRevoked)
+}
+
+func TestRevoker_Grace(t *testing.T) {
+	now := time.Unix(1000, 0)
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	// Decode с WithLeeway(time.Minute) принимает токен еще минуту после истечения
+	r, err := New(openRevoker(t).db, jwt.WithRevocationGrace(time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, r.Revoke("a", now))
+
+	now = now.Add(59 * time.Second)
+	revoked, err := r.IsRevoked("a")
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	now = now.Add(time.Second)
+	revoked, err = r.IsRevoked("a")
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestRevoker_StartPurge(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	r := openRevoker(t)
+	require.NoError(t, r.Revoke("a", time.Now().Add(-jwt.DefaultRevocationGrace-time.Second)))
+
+	stop := r.StartPurge(time.Millisecond)
+	require.Eventually(t, func() bool {
+		var n int
+		_ = r.db.View(func(tx *bolt.Tx) error {
+			n = tx.Bucket(bucket).Stats().KeyN
+			return nil
+		})
+		return n == 0
+	}, time.Second, time.Millisecond)
+	stop()
+}
@@ -0,0 +1,132 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrTokenNotValidYet      = errors.New("token is not valid yet")
+	ErrTokenUsedBeforeIssued = errors.New("token used before issued")
+	ErrInvalidIssuer         = errors.New("invalid issuer")
+	ErrInvalidSubject        = errors.New("invalid subject")
+	ErrInvalidAudience       = errors.New("invalid audience")
+	ErrInvalidID             = errors.New("invalid token id")
+)
+
+// Claims - зарегистрированные поля токена (RFC 7519, раздел 4.1).
+// Время хранится в секундах с начала эпохи
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt *int64   `json:"exp,omitempty"`
+	NotBefore *int64   `json:"nbf,omitempty"`
+	IssuedAt  *int64   `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+}
+
+var jsonNull = []byte("null")
+
+// Audience - получатели токена. Один получатель кодируется строкой, несколько - массивом
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, jsonNull) {
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Contains проверяет, что среди получателей есть хотя бы один из `aud`
+func (a Audience) Contains(aud ...string) bool {
+	for _, expected := range aud {
+		for _, v := range a {
+			if v == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claims заполняет зарегистрированные поля для нового токена
+func (c *config) claims() (Claims, error) {
+	exp, err := c.expiration()
+	if err != nil {
+		return Claims{}, err
+	}
+	res := Claims{
+		Issuer:    c.Issuer,
+		Subject:   c.Subject,
+		Audience:  c.Audience,
+		ExpiresAt: exp,
+		ID:        c.ID,
+	}
+	if c.NotBefore != nil {
+		nbf := c.NotBefore.Unix()
+		res.NotBefore = &nbf
+	}
+	if c.IssuedAt {
+		iat := timeFunc().Unix()
+		res.IssuedAt = &iat
+	}
+	return res, nil
+}
+
+// validate проверяет зарегистрированные поля токена с учетом допустимого расхождения часов
+// и то, что токен не отозван
+func (c *config) validate(claims *Claims) error {
+	now := timeFunc()
+	if claims.ExpiresAt != nil && !now.Before(time.Unix(*claims.ExpiresAt, 0).Add(c.Leeway)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Add(c.Leeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return ErrTokenNotValidYet
+	}
+	if claims.IssuedAt != nil && now.Add(c.Leeway).Before(time.Unix(*claims.IssuedAt, 0)) {
+		return ErrTokenUsedBeforeIssued
+	}
+	if c.Issuer != "" && claims.Issuer != c.Issuer {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, claims.Issuer)
+	}
+	if c.Subject != "" && claims.Subject != c.Subject {
+		return fmt.Errorf("%w: %q", ErrInvalidSubject, claims.Subject)
+	}
+	if len(c.Audience) != 0 && !claims.Audience.Contains(c.Audience...) {
+		return fmt.Errorf("%w: %q", ErrInvalidAudience, []string(claims.Audience))
+	}
+	if c.ID != "" && claims.ID != c.ID {
+		return fmt.Errorf("%w: %q", ErrInvalidID, claims.ID)
+	}
+	if c.Revoker != nil && claims.ID != "" {
+		revoked, err := c.Revoker.IsRevoked(claims.ID)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return fmt.Errorf("%w: %q", ErrTokenRevoked, claims.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package jwt
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudience_JSON(t *testing.T) {
+	raw, err := json.Marshal(Audience{"a"})
+	require.NoError(t, err)
+	require.JSONEq(t, `"a"`, string(raw))
+
+	raw, err = json.Marshal(Audience{"a", "b"})
+	require.NoError(t, err)
+	require.JSONEq(t, `["a","b"]`, string(raw))
+
+	var aud Audience
+	require.NoError(t, json.Unmarshal([]byte(`"a"`), &aud))
+	require.Equal(t, Audience{"a"}, aud)
+	require.NoError(t, json.Unmarshal([]byte(`["a","b"]`), &aud))
+	require.Equal(t, Audience{"a", "b"}, aud)
+	require.Error(t, json.Unmarshal([]byte(`42`), &aud))
+}
+
+func TestClaims(t *testing.T) {
+	now := time.Unix(1000, 0)
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	key := []byte("secret")
+	token, err := Encode("hello",
+		WithSignMethod(HS256), WithKey(key),
+		WithIssuer("auth"), WithSubject("user"), WithAudience("api", "web"),
+		WithNotBefore(now.Add(time.Minute)), WithIssuedAt(), WithID("42"), WithTTL(time.Hour),
+	)
+	require.NoError(t, err)
+
+	var claims Claims
+	var data string
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, Decode(token, &data,
+		WithSignMethod(HS256), WithKey(key),
+		WithIssuer("auth"), WithSubject("user"), WithAudience("web"), WithID("42"), WithClaims(&claims),
+	))
+	require.Equal(t, "hello", data)
+	require.Equal(t, Claims{
+		Issuer:    "auth",
+		Subject:   "user",
+		Audience:  Audience{"api", "web"},
+		ExpiresAt: int64Ptr(4600),
+		NotBefore: int64Ptr(1060),
+		IssuedAt:  int64Ptr(1000),
+		ID:        "42",
+	}, claims)
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func TestClaims_Errors(t *testing.T) {
+	now := time.Unix(1000, 0)
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	key := []byte("secret")
+	encode := func(opts ...Option) []byte {
+		token, err := Encode("hello", append([]Option{WithSignMethod(HS256), WithKey(key)}, opts...)...)
+		require.NoError(t, err)
+		return token
+	}
+	decode := func(token []byte, opts ...Option) error {
+		var data string
+		return Decode(token, &data, append([]Option{WithSignMethod(HS256), WithKey(key)}, opts...)...)
+	}
+
+	for _, tc := range []struct {
+		Name   string
+		Encode []Option
+		Decode []Option
+		Shift  time.Duration
+		Err    error
+	}{
+		{Name: "issuer", Encode: []Option{WithIssuer("a")}, Decode: []Option{WithIssuer("b")}, Err: ErrInvalidIssuer},
+		{Name: "no issuer", Decode: []Option{WithIssuer("b")}, Err: ErrInvalidIssuer},
+		{Name: "subject", Encode: []Option{WithSubject("a")}, Decode: []Option{WithSubject("b")}, Err: ErrInvalidSubject},
+		{Name: "audience", Encode: []Option{WithAudience("a", "b")}, Decode: []Option{WithAudience("c")}, Err: ErrInvalidAudience},
+		{Name: "no audience", Decode: []Option{WithAudience("c")}, Err: ErrInvalidAudience},
+		{Name: "id", Encode: []Option{WithID("1")}, Decode: []Option{WithID("2")}, Err: ErrInvalidID},
+		{Name: "expired", Encode: []Option{WithTTL(time.Minute)}, Shift: time.Minute, Err: ErrTokenExpired},
+		{Name: "expired leeway", Encode: []Option{WithTTL(time.Minute)}, Decode: []Option{WithLeeway(10 * time.Second)},
+			Shift: 70 * time.Second, Err: ErrTokenExpired},
+		{Name: "not expired leeway", Encode: []Option{WithTTL(time.Minute)}, Decode: []Option{WithLeeway(10 * time.Second)},
+			Shift: 69 * time.Second},
+		{Name: "not before", Encode: []Option{WithNotBefore(now.Add(time.Minute))}, Err: ErrTokenNotValidYet},
+		{Name: "not before leeway", Encode: []Option{WithNotBefore(now.Add(time.Minute))}, Decode: []Option{WithLeeway(time.Minute)}},
+		{Name: "issued at", Encode: []Option{WithIssuedAt()}, Shift: -time.Second, Err: ErrTokenUsedBeforeIssued},
+		{Name: "issued at leeway", Encode: []Option{WithIssuedAt()}, Decode: []Option{WithLeeway(time.Second)}, Shift: -time.Second},
+	} {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			now = time.Unix(1000, 0)
+			token := encode(tc.Encode...)
+			now = now.Add(tc.Shift)
+			err := decode(token, tc.Decode...)
+			if tc.Err != nil {
+				require.ErrorIs(t, err, tc.Err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package jwt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// seedTokens добавляет в корпус токены из табличных тестов
+func seedTokens(f *testing.F) {
+	for _, tc := range EncodeTestCases {
+		if tc.Token != "" {
+			f.Add([]byte(tc.Token))
+		}
+	}
+	for _, tc := range DecodeTestCases {
+		f.Add([]byte(tc.Token))
+	}
+}
+
+var decodeErrors = []error{
+	ErrInvalidToken, ErrTokenTooLarge, ErrInvalidSignMethod, ErrSignatureInvalid,
+	ErrSignMethodMismatched, ErrTokenExpired, ErrTokenNotValidYet, ErrTokenUsedBeforeIssued,
+}
+
+func FuzzDecode(f *testing.F) {
+	seedTokens(f)
+	f.Add([]byte("eyJhbGciOiJub25lIn0.eyJkIjoxfQ."))
+	timeFunc = func() time.Time { return time.Unix(0, 0) }
+	defer func() { timeFunc = time.Now }()
+
+	f.Fuzz(func(t *testing.T, token []byte) {
+		var data interface{}
+		err := Decode(token, &data, WithSignMethod(HS256), WithKey([]byte("secret-key1")))
+		if err == nil {
+			return
+		}
+		for _, expected := range decodeErrors {
+			if errors.Is(err, expected) {
+				return
+			}
+		}
+		t.Fatalf("unexpected error for %q: %v", token, err)
+	})
+}
+
+func FuzzDecodeBase64(f *testing.F) {
+	seedTokens(f)
+	f.Add([]byte("eyJ9"))
+	f.Add([]byte("_-8"))
+
+	f.Fuzz(func(t *testing.T, segment []byte) {
+		raw, err := decodeBase64(segment)
+		if err != nil {
+			require.ErrorIs(t, err, ErrInvalidToken)
+			return
+		}
+		// У каждого значения ровно одно представление
+		var b bytes.Buffer
+		writeBase64(&b, raw)
+		require.Equal(t, string(segment), b.String())
+	})
+}
+
+func FuzzReadHeader(f *testing.F) {
+	for _, tc := range DecodeTestCases {
+		f.Add([]byte(strings.Split(tc.Token, ".")[0]))
+	}
+	f.Add([]byte("eyJhbGciOiJub25lIn0"))
+	cfg := newConfig(nil)
+
+	f.Fuzz(func(t *testing.T, segment []byte) {
+		var h Header
+		if err := cfg.readHeader(segment, &h); err != nil {
+			return
+		}
+		require.False(t, strings.EqualFold(string(h.Alg), "none"))
+		require.LessOrEqual(t, len(segment), cfg.Limits.HeaderSize)
+	})
+}
+
+func TestDecode_Unsecured(t *testing.T) {
+	for _, alg := range []string{"none", "None", "NONE"} {
+		var b bytes.Buffer
+		require.NoError(t, writeSegment(&b, map[string]string{"alg": alg, "typ": tokenType}))
+		b.WriteString(".eyJkIjoxfQ.")
+		var data int
+		err := Decode(b.Bytes(), &data, WithSignMethod(HS256), WithKey([]byte("secret")))
+		require.ErrorIs(t, err, ErrInvalidSignMethod)
+	}
+}
+
+func TestDecode_Limits(t *testing.T) {
+	timeFunc = time.Now
+	key := []byte("secret")
+	decode := func(token []byte, opts ...Option) error {
+		var data interface{}
+		return Decode(token, &data, append([]Option{WithSignMethod(HS256), WithKey(key)}, opts...)...)
+	}
+
+	token, err := Encode(strings.Repeat("a", 1000), WithSignMethod(HS256), WithKey(key))
+	require.NoError(t, err)
+	require.NoError(t, decode(token))
+	require.ErrorIs(t, decode(token, WithLimits(Limits{TokenSize: 100})), ErrTokenTooLarge)
+	require.ErrorIs(t, decode(token, WithLimits(Limits{HeaderSize: 10})), ErrTokenTooLarge)
+
+	nested := strings.Repeat("[", 40) + strings.Repeat("]", 40)
+	token, err = Encode(rawJSON(nested), WithSignMethod(HS256), WithKey(key))
+	require.NoError(t, err)
+	require.ErrorIs(t, decode(token), ErrInvalidToken)
+	require.NoError(t, decode(token, WithLimits(Limits{Depth: 41})))
+
+	// Скобки внутри строк не учитываются
+	token, err = Encode(strings.Repeat("[", 100), WithSignMethod(HS256), WithKey(key))
+	require.NoError(t, err)
+	require.NoError(t, decode(token))
+
+	_, _, err = ParseUnverified(bytes.Repeat([]byte("a"), defaultMaxTokenSize+1))
+	require.ErrorIs(t, err, ErrTokenTooLarge)
+}
+
+type rawJSON string
+
+func (r rawJSON) MarshalJSON() ([]byte, error) {
+	return []byte(r), nil
+}
+
+func TestDecode_NonCanonicalBase64(t *testing.T) {
+	key := []byte("secret-key1")
+	token := []byte(EncodeTestCases[0].Token)
+	require.NoError(t, Decode(token, new(interface{}), WithSignMethod(HS256), WithKey(key)))
+
+	// Последний символ подписи несет лишние биты: их изменение не должно давать другой валидный токен
+	last := token[len(token)-1]
+	for _, c := range []byte("RSTUVWXYZ") {
+		if c == last {
+			continue
+		}
+		token[len(token)-1] = c
+		require.Error(t, Decode(token, new(interface{}), WithSignMethod(HS256), WithKey(key)))
+	}
+}
+
+func TestSigner_KeyMismatched(t *testing.T) {
+	_, err := Encode(1, WithSignMethod(HS256), WithPrivateKey(testP256Key))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	_, err = Encode(1, WithSignMethod(ES256), WithKey([]byte("secret")), WithPrivateKey(testP256Key))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	_, err = Encode(1, WithSignMethod(RS256), WithKey([]byte("secret")))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	_, err = Encode(1, WithSignMethod(EdDSA), WithKey([]byte("secret")))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+}
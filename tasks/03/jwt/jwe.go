@@ -0,0 +1,312 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// KeyAlgorithm - способ получения ключа шифрования содержимого (заголовок `alg` JWE)
+type KeyAlgorithm string
+
+const (
+	Dir    KeyAlgorithm = "dir"
+	A128KW KeyAlgorithm = "A128KW"
+	A256KW KeyAlgorithm = "A256KW"
+)
+
+// EncryptMethod - алгоритм шифрования содержимого (заголовок `enc` JWE)
+type EncryptMethod string
+
+const A256GCM EncryptMethod = "A256GCM"
+
+var (
+	ErrDecryptionFailed        = errors.New("decryption failed")
+	ErrEncryptMethodMismatched = errors.New("encrypt method mismatched")
+)
+
+// Заголовок `cty` вложенного подписанного токена
+const nestedContentType = "JWT"
+
+const (
+	jweParts     = 5
+	cekSize      = 32
+	gcmNonceSize = 12
+)
+
+type jweHeader struct {
+	Alg KeyAlgorithm  `json:"alg"`
+	Enc EncryptMethod `json:"enc"`
+	Typ string        `json:"typ,omitempty"`
+	Cty string        `json:"cty,omitempty"`
+	Kid string        `json:"kid,omitempty"`
+}
+
+// EncodeEncrypted создает токен в формате JWE. Если задан метод подписи,
+// данные сначала подписываются через Encode, и шифруется уже подписанный токен
+func EncodeEncrypted(data interface{}, opts ...Option) ([]byte, error) {
+	cfg := newConfig(opts)
+	h := jweHeader{Alg: cfg.KeyAlgorithm, Enc: A256GCM, Typ: tokenType, Kid: cfg.KeyID}
+
+	var plaintext []byte
+	if cfg.SignMethod != "" || cfg.KeySet != nil {
+		token, err := Encode(data, opts...)
+		if err != nil {
+			return nil, err
+		}
+		plaintext, h.Typ, h.Cty = token, "", nestedContentType
+	} else {
+		claims, err := cfg.claims()
+		if err != nil {
+			return nil, err
+		}
+		if plaintext, err = cfg.marshalPayload(data, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	cek, encryptedKey, err := newContentKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, gcmNonceSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+
+	var token bytes.Buffer
+	if err := writeSegment(&token, h); err != nil {
+		return nil, err
+	}
+	ciphertext, tag, err := encryptContent(cek, iv, token.Bytes(), plaintext)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range [][]byte{encryptedKey, iv, ciphertext, tag} {
+		token.Write(separator)
+		writeBase64(&token, part)
+	}
+	return token.Bytes(), nil
+}
+
+// DecodeEncrypted расшифровывает токен в формате JWE и заполняет `data`. Вложенный
+// подписанный токен проверяется через Decode с теми же опциями. Если задан метод
+// подписи, токен обязан быть вложенным
+func DecodeEncrypted(token []byte, data interface{}, opts ...Option) error {
+	cfg := newConfig(opts)
+	if err := cfg.Limits.checkToken(token); err != nil {
+		return err
+	}
+	parts := bytes.Split(token, separator)
+	if len(parts) != jweParts {
+		return fmt.Errorf("%w: expected %d parts, got %d", ErrInvalidToken, jweParts, len(parts))
+	}
+
+	var h jweHeader
+	if err := cfg.readHeader(parts[0], &h); err != nil {
+		return err
+	}
+	if h.Alg != cfg.KeyAlgorithm || h.Enc != A256GCM {
+		return fmt.Errorf("%w: token is encrypted with %q/%q", ErrEncryptMethodMismatched, h.Alg, h.Enc)
+	}
+	nested := h.Cty == nestedContentType
+	if !nested && (cfg.SignMethod != "" || cfg.KeySet != nil) {
+		return fmt.Errorf("%w: token is not signed", ErrSignMethodMismatched)
+	}
+
+	decoded := make([][]byte, 0, jweParts-1)
+	for _, part := range parts[1:] {
+		raw, err := decodeBase64(part)
+		if err != nil {
+			return err
+		}
+		decoded = append(decoded, raw)
+	}
+	encryptedKey, iv, ciphertext, tag := decoded[0], decoded[1], decoded[2], decoded[3]
+
+	cek, err := contentKey(cfg, encryptedKey)
+	if err != nil {
+		return err
+	}
+	plaintext, err := decryptContent(cek, iv, parts[0], ciphertext, tag)
+	if err != nil {
+		return err
+	}
+	if nested {
+		return Decode(plaintext, data, opts...)
+	}
+
+	p, err := cfg.unmarshalPayload(plaintext)
+	if err != nil {
+		return err
+	}
+	if err := cfg.validate(&p.Claims); err != nil {
+		return err
+	}
+	if err := cfg.unmarshalData(p.Data, data); err != nil {
+		return err
+	}
+	if cfg.Claims != nil {
+		*cfg.Claims = p.Claims
+	}
+	return nil
+}
+
+// keyEncryptionKey проверяет, что ключ из конфигурации подходит для алгоритма
+func keyEncryptionKey(cfg *config) ([]byte, error) {
+	size, ok := map[KeyAlgorithm]int{Dir: cekSize, A128KW: 16, A256KW: 32}[cfg.KeyAlgorithm]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported key algorithm %q", ErrConfigurationMalformed, cfg.KeyAlgorithm)
+	}
+	if len(cfg.EncryptionKey) != size {
+		return nil, fmt.Errorf("%w: %s requires %d byte key", ErrConfigurationMalformed, cfg.KeyAlgorithm, size)
+	}
+	return cfg.EncryptionKey, nil
+}
+
+// newContentKey возвращает ключ шифрования содержимого и его зашифрованное представление для токена
+func newContentKey(cfg *config) ([]byte, []byte, error) {
+	kek, err := keyEncryptionKey(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.KeyAlgorithm == Dir {
+		return kek, nil, nil
+	}
+	cek := make([]byte, cekSize)
+	if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := wrapKey(kek, cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cek, wrapped, nil
+}
+
+// contentKey восстанавливает ключ шифрования содержимого из токена
+func contentKey(cfg *config, encryptedKey []byte) ([]byte, error) {
+	kek, err := keyEncryptionKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.KeyAlgorithm == Dir {
+		if len(encryptedKey) != 0 {
+			return nil, fmt.Errorf("%w: unexpected encrypted key", ErrInvalidToken)
+		}
+		return kek, nil
+	}
+	cek, err := unwrapKey(kek, encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(cek) != cekSize {
+		return nil, ErrDecryptionFailed
+	}
+	return cek, nil
+}
+
+// encryptContent шифрует `plaintext` алгоритмом A256GCM, `aad` - закодированный заголовок
+func encryptContent(cek, iv, aad, plaintext []byte) ([]byte, []byte, error) {
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, aad)
+	split := len(sealed) - gcm.Overhead()
+	return sealed[:split], sealed[split:], nil
+}
+
+func decryptContent(cek, iv, aad, ciphertext, tag []byte) ([]byte, error) {
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != gcm.NonceSize() || len(tag) != gcm.Overhead() {
+		return nil, fmt.Errorf("%w: invalid iv or tag size", ErrInvalidToken)
+	}
+	sealed := make([]byte, 0, len(ciphertext)+len(tag))
+	sealed = append(append(sealed, ciphertext...), tag...)
+	plaintext, err := gcm.Open(nil, iv, sealed, aad)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Начальное значение AES Key Wrap (RFC 3394, раздел 2.2.3.1)
+var keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+const keyWrapRounds = 6
+
+// wrapKey шифрует ключ `key` ключом `kek` по RFC 3394
+func wrapKey(kek, key []byte) ([]byte, error) {
+	if len(key)%8 != 0 || len(key) < 16 {
+		return nil, fmt.Errorf("%w: key size must be a multiple of 8", ErrConfigurationMalformed)
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(key) / 8
+	res := make([]byte, 8+len(key))
+	copy(res, keyWrapIV)
+	copy(res[8:], key)
+
+	buf := make([]byte, aes.BlockSize)
+	for j := 0; j < keyWrapRounds; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf, res[:8])
+			copy(buf[8:], res[8*i:8*i+8])
+			block.Encrypt(buf, buf)
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(res[:8], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(res[8*i:8*i+8], buf[8:])
+		}
+	}
+	return res, nil
+}
+
+// unwrapKey расшифровывает ключ, зашифрованный wrapKey
+func unwrapKey(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped)%8 != 0 || len(wrapped) < 24 {
+		return nil, ErrDecryptionFailed
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(wrapped)/8 - 1
+	res := make([]byte, len(wrapped))
+	copy(res, wrapped)
+
+	buf := make([]byte, aes.BlockSize)
+	for j := keyWrapRounds - 1; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(res[:8])^t)
+			copy(buf[8:], res[8*i:8*i+8])
+			block.Decrypt(buf, buf)
+			copy(res[:8], buf[:8])
+			copy(res[8*i:8*i+8], buf[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(res[:8], keyWrapIV) != 1 {
+		return nil, ErrDecryptionFailed
+	}
+	return res[8:], nil
+}
@@ -0,0 +1,143 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustDecodeBase64(s string) []byte {
+	raw, err := encoding.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
+
+func TestEncryptContent_RFC7516(t *testing.T) {
+	// RFC 7516, приложение A.1: шифрование содержимого A256GCM
+	cek := []byte{177, 161, 244, 128, 84, 143, 225, 115, 63, 180, 3, 255, 107, 154, 212, 246,
+		138, 7, 110, 91, 112, 46, 34, 105, 47, 130, 203, 46, 122, 234, 64, 252}
+	iv := []byte{227, 197, 117, 252, 2, 219, 233, 68, 180, 225, 77, 219}
+	aad := []byte("eyJhbGciOiJSU0EtT0FFUCIsImVuYyI6IkEyNTZHQ00ifQ")
+	plaintext := []byte("The true sign of intelligence is not knowledge but imagination.")
+
+	ciphertext, tag, err := encryptContent(cek, iv, aad, plaintext)
+	require.NoError(t, err)
+	require.Equal(t, "5eym8TW_c8SuK0ltJ3rpYIzOeDQz7TALvtu6UG9oMo4vpzs9tX_EFShS8iB7j6jiSdiwkIr3ajwQzaBtQD_A",
+		encoding.EncodeToString(ciphertext))
+	require.Equal(t, "XFBoMYUZodetZdvTiFvSkQ", encoding.EncodeToString(tag))
+
+	decrypted, err := decryptContent(cek, iv, aad, ciphertext, tag)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, decrypted)
+
+	_, err = decryptContent(cek, iv, aad[1:], ciphertext, tag)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+}
+
+func TestWrapKey_RFC7516(t *testing.T) {
+	// RFC 7516, приложение A.3: шифрование ключа A128KW
+	kek := mustDecodeBase64("GawgguFyGrWKav7AX4VKUg")
+	cek := []byte{4, 211, 31, 197, 84, 157, 252, 254, 11, 100, 157, 250, 63, 170, 106, 206,
+		107, 124, 212, 45, 111, 107, 9, 219, 200, 177, 0, 240, 143, 156, 44, 207}
+
+	wrapped, err := wrapKey(kek, cek)
+	require.NoError(t, err)
+	require.Equal(t, "6KB707dM9YTIgHtLvtgWQ8mKwboJW3of9locizkDTHzBC2IlrT1oOQ", encoding.EncodeToString(wrapped))
+
+	unwrapped, err := unwrapKey(kek, wrapped)
+	require.NoError(t, err)
+	require.Equal(t, cek, unwrapped)
+
+	wrapped[0] ^= 1
+	_, err = unwrapKey(kek, wrapped)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+}
+
+func TestWrapKey_RFC3394(t *testing.T) {
+	// RFC 3394, раздел 4.6: 256-битный ключ шифруется 256-битным ключом
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F")
+	key, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F")
+	wrapped, err := wrapKey(kek, key)
+	require.NoError(t, err)
+	require.Equal(t, "28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21",
+		hex.EncodeToString(wrapped))
+}
+
+var jweTestCases = []struct {
+	Alg KeyAlgorithm
+	Key []byte
+}{
+	{Alg: Dir, Key: bytes.Repeat([]byte{1}, 32)},
+	{Alg: A128KW, Key: bytes.Repeat([]byte{2}, 16)},
+	{Alg: A256KW, Key: bytes.Repeat([]byte{3}, 32)},
+}
+
+func TestEncrypted(t *testing.T) {
+	timeFunc = time.Now
+	for _, tc := range jweTestCases {
+		tc := tc
+		t.Run(string(tc.Alg), func(t *testing.T) {
+			opts := []Option{WithKeyAlgorithm(tc.Alg), WithEncryptionKey(tc.Key)}
+			token, err := EncodeEncrypted(map[string]string{"email": "user@example.com"}, append(opts, WithTTL(time.Hour))...)
+			require.NoError(t, err)
+			require.Equal(t, 4, bytes.Count(token, separator))
+			require.NotContains(t, string(token), "email")
+
+			var data map[string]string
+			require.NoError(t, DecodeEncrypted(token, &data, opts...))
+			require.Equal(t, "user@example.com", data["email"])
+
+			other := append([]byte{}, tc.Key...)
+			other[0] ^= 1
+			err = DecodeEncrypted(token, &data, WithKeyAlgorithm(tc.Alg), WithEncryptionKey(other))
+			require.ErrorIs(t, err, ErrDecryptionFailed)
+
+			err = DecodeEncrypted(token, &data, opts[1:]...)
+			require.ErrorIs(t, err, ErrEncryptMethodMismatched)
+
+			// Подписанный токен не расшифровывается
+			err = DecodeEncrypted(token, &data, append(opts, WithSignMethod(HS256), WithKey([]byte("k")))...)
+			require.ErrorIs(t, err, ErrSignMethodMismatched)
+		})
+	}
+}
+
+func TestEncrypted_Nested(t *testing.T) {
+	timeFunc = time.Now
+	opts := []Option{
+		WithKeyAlgorithm(A256KW), WithEncryptionKey(bytes.Repeat([]byte{4}, 32)),
+		WithSignMethod(ES256), WithPrivateKey(testP256Key), WithIssuer("auth"),
+	}
+	token, err := EncodeEncrypted("secret", opts...)
+	require.NoError(t, err)
+
+	var h jweHeader
+	require.NoError(t, readSegment(token[:bytes.IndexByte(token, '.')], &h))
+	require.Equal(t, nestedContentType, h.Cty)
+
+	var data string
+	require.NoError(t, DecodeEncrypted(token, &data, opts...))
+	require.Equal(t, "secret", data)
+
+	err = DecodeEncrypted(token, &data, append(opts, WithIssuer("other"))...)
+	require.ErrorIs(t, err, ErrInvalidIssuer)
+	err = DecodeEncrypted(token, &data, append(opts, WithPrivateKey(mustECDSAKey(testP256Key.Curve)))...)
+	require.ErrorIs(t, err, ErrSignatureInvalid)
+}
+
+func TestEncrypted_Errors(t *testing.T) {
+	timeFunc = time.Now
+	_, err := EncodeEncrypted("x", WithKeyAlgorithm(A128KW), WithEncryptionKey(make([]byte, 32)))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	_, err = EncodeEncrypted("x", WithKeyAlgorithm("RSA-OAEP"), WithEncryptionKey(make([]byte, 32)))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+
+	var data string
+	err = DecodeEncrypted([]byte("a.b.c"), &data, WithKeyAlgorithm(Dir), WithEncryptionKey(make([]byte, 32)))
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,218 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrInvalidKey = errors.New("invalid key")
+
+// JWK - ключ в формате JSON Web Key (RFC 7517). Key содержит []byte для HMAC,
+// *rsa.PrivateKey, *rsa.PublicKey, *ecdsa.PrivateKey, *ecdsa.PublicKey,
+// ed25519.PrivateKey или ed25519.PublicKey
+type JWK struct {
+	KeyID     string
+	Algorithm SignMethod
+	Use       string
+	Key       interface{}
+}
+
+// rawJWK - представление JWK в json, ключевой материал закодирован в base64url
+type rawJWK struct {
+	Kty string     `json:"kty"`
+	Kid string     `json:"kid,omitempty"`
+	Alg SignMethod `json:"alg,omitempty"`
+	Use string     `json:"use,omitempty"`
+	Crv string     `json:"crv,omitempty"`
+	K   *b64       `json:"k,omitempty"`
+	N   *b64       `json:"n,omitempty"`
+	E   *b64       `json:"e,omitempty"`
+	X   *b64       `json:"x,omitempty"`
+	Y   *b64       `json:"y,omitempty"`
+	D   *b64       `json:"d,omitempty"`
+	P   *b64       `json:"p,omitempty"`
+	Q   *b64       `json:"q,omitempty"`
+}
+
+// b64 - байты, которые в json кодируются как base64url без выравнивания
+type b64 []byte
+
+func (b b64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encoding.EncodeToString(b))
+}
+
+func (b *b64) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	raw, err := encoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*b = raw
+	return nil
+}
+
+func newB64(raw []byte) *b64 {
+	b := b64(raw)
+	return &b
+}
+
+func (b *b64) bigInt() *big.Int {
+	if b == nil {
+		return nil
+	}
+	return new(big.Int).SetBytes(*b)
+}
+
+func (k JWK) MarshalJSON() ([]byte, error) {
+	raw := rawJWK{Kid: k.KeyID, Alg: k.Algorithm, Use: k.Use}
+	switch key := k.Key.(type) {
+	case []byte:
+		raw.Kty, raw.K = "oct", newB64(key)
+	case *rsa.PrivateKey:
+		setRSAPublic(&raw, &key.PublicKey)
+		raw.D = newB64(key.D.Bytes())
+		if len(key.Primes) == 2 {
+			raw.P, raw.Q = newB64(key.Primes[0].Bytes()), newB64(key.Primes[1].Bytes())
+		}
+	case *rsa.PublicKey:
+		setRSAPublic(&raw, key)
+	case *ecdsa.PrivateKey:
+		if err := setECDSAPublic(&raw, &key.PublicKey); err != nil {
+			return nil, err
+		}
+		raw.D = newB64(key.D.FillBytes(make([]byte, curveSize(key.Curve))))
+	case *ecdsa.PublicKey:
+		if err := setECDSAPublic(&raw, key); err != nil {
+			return nil, err
+		}
+	case ed25519.PrivateKey:
+		raw.Kty, raw.Crv = "OKP", "Ed25519"
+		raw.X = newB64(key.Public().(ed25519.PublicKey))
+		raw.D = newB64(key.Seed())
+	case ed25519.PublicKey:
+		raw.Kty, raw.Crv, raw.X = "OKP", "Ed25519", newB64(key)
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrInvalidKey, k.Key)
+	}
+	return json.Marshal(raw)
+}
+
+func (k *JWK) UnmarshalJSON(data []byte) error {
+	var raw rawJWK
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	key, err := raw.key()
+	if err != nil {
+		return err
+	}
+	*k = JWK{KeyID: raw.Kid, Algorithm: raw.Alg, Use: raw.Use, Key: key}
+	return nil
+}
+
+func (raw *rawJWK) key() (interface{}, error) {
+	switch raw.Kty {
+	case "oct":
+		if raw.K == nil || len(*raw.K) == 0 {
+			return nil, fmt.Errorf("%w: missing oct key", ErrInvalidKey)
+		}
+		return []byte(*raw.K), nil
+	case "RSA":
+		if raw.N == nil || raw.E == nil {
+			return nil, fmt.Errorf("%w: missing rsa modulus or exponent", ErrInvalidKey)
+		}
+		e := raw.E.bigInt()
+		if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("%w: invalid rsa exponent", ErrInvalidKey)
+		}
+		public := rsa.PublicKey{N: raw.N.bigInt(), E: int(e.Int64())}
+		if raw.D == nil {
+			return &public, nil
+		}
+		private := &rsa.PrivateKey{PublicKey: public, D: raw.D.bigInt()}
+		if raw.P != nil && raw.Q != nil {
+			private.Primes = []*big.Int{raw.P.bigInt(), raw.Q.bigInt()}
+		}
+		if err := private.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		private.Precompute()
+		return private, nil
+	case "EC":
+		curve, ok := curves[raw.Crv]
+		if !ok || raw.X == nil || raw.Y == nil {
+			return nil, fmt.Errorf("%w: unsupported curve %q", ErrInvalidKey, raw.Crv)
+		}
+		public := ecdsa.PublicKey{Curve: curve, X: raw.X.bigInt(), Y: raw.Y.bigInt()}
+		if !curve.IsOnCurve(public.X, public.Y) {
+			return nil, fmt.Errorf("%w: point is not on curve", ErrInvalidKey)
+		}
+		if raw.D == nil {
+			return &public, nil
+		}
+		return &ecdsa.PrivateKey{PublicKey: public, D: raw.D.bigInt()}, nil
+	case "OKP":
+		if raw.Crv != "Ed25519" || raw.X == nil || len(*raw.X) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: unsupported okp key", ErrInvalidKey)
+		}
+		if raw.D == nil {
+			return ed25519.PublicKey(*raw.X), nil
+		}
+		if len(*raw.D) != ed25519.SeedSize {
+			return nil, fmt.Errorf("%w: invalid ed25519 seed", ErrInvalidKey)
+		}
+		return ed25519.NewKeyFromSeed(*raw.D), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %q", ErrInvalidKey, raw.Kty)
+	}
+}
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+}
+
+func curveSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+func setRSAPublic(raw *rawJWK, key *rsa.PublicKey) {
+	raw.Kty = "RSA"
+	raw.N = newB64(key.N.Bytes())
+	raw.E = newB64(big.NewInt(int64(key.E)).Bytes())
+}
+
+func setECDSAPublic(raw *rawJWK, key *ecdsa.PublicKey) error {
+	raw.Kty, raw.Crv = "EC", key.Curve.Params().Name
+	if _, ok := curves[raw.Crv]; !ok {
+		return fmt.Errorf("%w: unsupported curve %q", ErrInvalidKey, raw.Crv)
+	}
+	size := curveSize(key.Curve)
+	raw.X = newB64(key.X.FillBytes(make([]byte, size)))
+	raw.Y = newB64(key.Y.FillBytes(make([]byte, size)))
+	return nil
+}
+
+// Public возвращает ключ без секретной части. Для HMAC ключей возвращает false
+func (k JWK) Public() (JWK, bool) {
+	switch key := k.Key.(type) {
+	case []byte:
+		return JWK{}, false
+	case *rsa.PrivateKey:
+		k.Key = &key.PublicKey
+	case *ecdsa.PrivateKey:
+		k.Key = &key.PublicKey
+	case ed25519.PrivateKey:
+		k.Key = key.Public()
+	}
+	return k, true
+}
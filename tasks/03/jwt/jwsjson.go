@@ -0,0 +1,245 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// SignaturePolicy определяет, сколько подписей токена в json-сериализации должно быть верными
+type SignaturePolicy int
+
+const (
+	// RequireAll - все подписи токена должны быть верными
+	RequireAll SignaturePolicy = iota
+	// RequireAny - достаточно одной верной подписи
+	RequireAny
+)
+
+// jwsSignature - подпись в JWS JSON Serialization (RFC 7515, раздел 7.2)
+type jwsSignature struct {
+	Protected string          `json:"protected"`
+	Header    *jwsUnprotected `json:"header,omitempty"`
+	Signature string          `json:"signature"`
+}
+
+// jwsUnprotected - незащищенный подписью заголовок. Из него берется только `kid`
+type jwsUnprotected struct {
+	Kid string `json:"kid,omitempty"`
+}
+
+// jwsGeneral - общая форма с произвольным числом подписей
+type jwsGeneral struct {
+	Payload    string         `json:"payload"`
+	Signatures []jwsSignature `json:"signatures"`
+}
+
+// jwsFlattened - упрощенная форма с единственной подписью
+type jwsFlattened struct {
+	Payload string `json:"payload"`
+	jwsSignature
+}
+
+// EncodeJSON подписывает данные каждым из ключей `signers` и возвращает токен в JWS General
+// JSON Serialization. С опцией WithFlattenedJSON используется упрощенная форма, в ней допустим
+// только один ключ. Метод подписи берется из ключа, для ключей без `alg` - из WithSignMethod
+func EncodeJSON(data interface{}, signers []JWK, opts ...Option) ([]byte, error) {
+	cfg := newConfig(opts)
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("%w: no signers", ErrConfigurationMalformed)
+	}
+	if cfg.FlattenedJSON && len(signers) != 1 {
+		return nil, fmt.Errorf("%w: flattened serialization allows one signer, got %d", ErrConfigurationMalformed, len(signers))
+	}
+	claims, err := cfg.claims()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := cfg.marshalPayload(data, claims)
+	if err != nil {
+		return nil, err
+	}
+	var payload bytes.Buffer
+	writeBase64(&payload, raw)
+
+	res := jwsGeneral{Payload: payload.String(), Signatures: make([]jwsSignature, 0, len(signers))}
+	for _, k := range signers {
+		signature, err := signJSON(cfg, k, payload.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		res.Signatures = append(res.Signatures, signature)
+	}
+	if cfg.FlattenedJSON {
+		return json.Marshal(jwsFlattened{Payload: res.Payload, jwsSignature: res.Signatures[0]})
+	}
+	return json.Marshal(res)
+}
+
+func signJSON(base *config, k JWK, payload []byte) (jwsSignature, error) {
+	alg := k.Algorithm
+	if alg == "" {
+		alg = base.SignMethod
+	}
+	cfg, err := base.withoutKeys().withJWK(k, alg)
+	if err != nil {
+		return jwsSignature{}, err
+	}
+	s, err := newSigner(cfg)
+	if err != nil {
+		return jwsSignature{}, err
+	}
+
+	var signed bytes.Buffer
+	if err := writeSegment(&signed, Header{Alg: cfg.SignMethod, Typ: tokenType, Kid: k.KeyID}); err != nil {
+		return jwsSignature{}, err
+	}
+	protected := signed.String()
+	signed.Write(separator)
+	signed.Write(payload)
+
+	signature, err := s.Sign(signed.Bytes())
+	if err != nil {
+		return jwsSignature{}, err
+	}
+	var b bytes.Buffer
+	writeBase64(&b, signature)
+	return jwsSignature{Protected: protected, Signature: b.String()}, nil
+}
+
+// DecodeJSON проверяет токен в JWS General или Flattened JSON Serialization и заполняет `data`.
+// Ключи выбираются из WithKeySet по `kid`, число требуемых верных подписей задается
+// через WithSignaturePolicy и WithRequiredKeys. Несколько подписей одним ключом не допускаются
+func DecodeJSON(token []byte, data interface{}, opts ...Option) error {
+	cfg := newConfig(opts)
+	if cfg.KeySet == nil {
+		return fmt.Errorf("%w: key set is required", ErrConfigurationMalformed)
+	}
+	if err := cfg.Limits.checkToken(token); err != nil {
+		return err
+	}
+	if err := cfg.Limits.checkDepth(token); err != nil {
+		return err
+	}
+	jws, err := readJSON(token)
+	if err != nil {
+		return err
+	}
+
+	var verified *Header
+	var firstErr error
+	// Подписи одним ключом нельзя засчитывать несколько раз
+	kids := make(map[string]bool, len(jws.Signatures))
+	for _, signature := range jws.Signatures {
+		h, err := readJSONHeader(cfg, signature)
+		if err == nil {
+			if _, ok := kids[h.Kid]; ok {
+				return fmt.Errorf("%w: several signatures with key %q", ErrInvalidToken, h.Kid)
+			}
+			kids[h.Kid] = false
+			err = verifyJSON(cfg, h, signature, []byte(jws.Payload))
+		}
+		if err != nil {
+			if cfg.SignaturePolicy == RequireAll {
+				return err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		kids[h.Kid] = true
+		if verified == nil {
+			verified = &h
+		}
+	}
+	if verified == nil {
+		return firstErr
+	}
+	for _, kid := range cfg.RequiredKeys {
+		if !kids[kid] {
+			return fmt.Errorf("%w: no valid signature with key %q", ErrSignatureInvalid, kid)
+		}
+	}
+
+	p, err := readPayload(cfg, []byte(jws.Payload))
+	if err != nil {
+		return err
+	}
+	if err := cfg.validate(&p.Claims); err != nil {
+		return err
+	}
+	if err := cfg.unmarshalData(p.Data, data); err != nil {
+		return err
+	}
+	if cfg.Header != nil {
+		*cfg.Header = *verified
+	}
+	if cfg.Claims != nil {
+		*cfg.Claims = p.Claims
+	}
+	return nil
+}
+
+// readJSON разбирает обе формы json-сериализации, приводя упрощенную к общей
+func readJSON(token []byte) (jwsGeneral, error) {
+	var doc struct {
+		jwsGeneral
+		jwsSignature
+	}
+	if err := json.Unmarshal(token, &doc); err != nil {
+		return jwsGeneral{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	res := doc.jwsGeneral
+	switch {
+	case doc.Signatures != nil && doc.Signature != "":
+		return res, fmt.Errorf("%w: both general and flattened signatures", ErrInvalidToken)
+	case doc.Signatures == nil:
+		res.Signatures = []jwsSignature{doc.jwsSignature}
+	}
+	if len(res.Signatures) == 0 {
+		return res, fmt.Errorf("%w: no signatures", ErrInvalidToken)
+	}
+	return res, nil
+}
+
+// readJSONHeader читает защищенный заголовок подписи, `kid` может быть в незащищенном
+func readJSONHeader(base *config, signature jwsSignature) (Header, error) {
+	var h Header
+	if err := base.readHeader([]byte(signature.Protected), &h); err != nil {
+		return h, err
+	}
+	if h.Kid == "" && signature.Header != nil {
+		h.Kid = signature.Header.Kid
+	}
+	return h, nil
+}
+
+// verifyJSON проверяет одну подпись с заголовком `h`
+func verifyJSON(base *config, h Header, signature jwsSignature, payload []byte) error {
+	k, err := base.KeySet.Key(h.Kid)
+	if err != nil {
+		return err
+	}
+	cfg, err := base.withoutKeys().withJWK(k, h.Alg)
+	if err != nil {
+		return err
+	}
+	s, err := newSigner(cfg)
+	if err != nil {
+		return err
+	}
+	if h.Alg != cfg.SignMethod {
+		return fmt.Errorf("%w: token is signed with %q", ErrSignMethodMismatched, h.Alg)
+	}
+
+	raw, err := decodeBase64([]byte(signature.Signature))
+	if err != nil {
+		return err
+	}
+	var signed bytes.Buffer
+	signed.WriteString(signature.Protected)
+	signed.Write(separator)
+	signed.Write(payload)
+	return s.Verify(signed.Bytes(), raw)
+}
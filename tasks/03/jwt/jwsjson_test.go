@@ -0,0 +1,168 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeJSON_General(t *testing.T) {
+	timeFunc = time.Now
+	signers := []JWK{
+		{KeyID: "hmac", Algorithm: HS256, Key: []byte("secret")},
+		{KeyID: "ec", Algorithm: ES256, Key: testP256Key},
+		{KeyID: "rsa", Algorithm: PS256, Key: testRSAKey},
+	}
+	token, err := EncodeJSON("hello", signers, WithIssuer("auth"))
+	require.NoError(t, err)
+
+	var doc jwsGeneral
+	require.NoError(t, json.Unmarshal(token, &doc))
+	require.Len(t, doc.Signatures, 3)
+
+	ks := &KeySet{Keys: append(signers[:2:2], JWK{KeyID: "rsa", Algorithm: PS256, Key: &testRSAKey.PublicKey})}
+	var data string
+	var claims Claims
+	require.NoError(t, DecodeJSON(token, &data, WithKeySet(ks), WithIssuer("auth"), WithClaims(&claims)))
+	require.Equal(t, "hello", data)
+	require.Equal(t, "auth", claims.Issuer)
+
+	// Каждая подпись совпадает с подписью компактного токена с тем же ключом
+	for i, s := range doc.Signatures {
+		compact := []byte(s.Protected + "." + doc.Payload + "." + s.Signature)
+		require.NoError(t, Decode(compact, &data, WithKeySet(ks)), i)
+	}
+}
+
+func TestEncodeJSON_Flattened(t *testing.T) {
+	timeFunc = time.Now
+	k := JWK{KeyID: "ed", Key: testEd25519Key}
+	token, err := EncodeJSON(42, []JWK{k}, WithSignMethod(EdDSA), WithFlattenedJSON())
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(token, &doc))
+	require.NotContains(t, doc, "signatures")
+	require.Contains(t, doc, "signature")
+
+	var data int
+	require.NoError(t, DecodeJSON(token, &data, WithKeySet(&KeySet{Keys: []JWK{k}}), WithSignMethod(EdDSA)))
+	require.Equal(t, 42, data)
+
+	_, err = EncodeJSON(42, []JWK{k, k}, WithSignMethod(EdDSA), WithFlattenedJSON())
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	_, err = EncodeJSON(42, nil)
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+}
+
+func TestDecodeJSON_Policy(t *testing.T) {
+	timeFunc = time.Now
+	ks := testKeySet()
+	token, err := EncodeJSON("hello", ks.Keys[:2])
+	require.NoError(t, err)
+
+	// Подпись ключом "ec" не проходит проверку
+	var doc jwsGeneral
+	require.NoError(t, json.Unmarshal(token, &doc))
+	raw := []byte(doc.Signatures[1].Signature)
+	tamper(raw)
+	doc.Signatures[1].Signature = string(raw)
+	tampered, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	var data string
+	require.ErrorIs(t, DecodeJSON(tampered, &data, WithKeySet(ks)), ErrSignatureInvalid)
+	require.NoError(t, DecodeJSON(tampered, &data, WithKeySet(ks), WithSignaturePolicy(RequireAny)))
+
+	// Ключ "hmac" неизвестен проверяющему
+	partial := &KeySet{Keys: ks.Keys[1:2]}
+	require.ErrorIs(t, DecodeJSON(token, &data, WithKeySet(partial)), ErrKeyNotFound)
+	var h Header
+	require.NoError(t, DecodeJSON(token, &data, WithKeySet(partial), WithSignaturePolicy(RequireAny), WithHeader(&h)))
+	require.Equal(t, "ec", h.Kid)
+
+	// Если верных подписей нет, возвращается первая ошибка
+	require.ErrorIs(t, DecodeJSON(tampered, &data, WithKeySet(partial), WithSignaturePolicy(RequireAny)), ErrKeyNotFound)
+}
+
+// keyProvider - набор ключей, который нельзя перечислить, как RemoteKeySet
+type keyProvider struct {
+	set *KeySet
+}
+
+func (p keyProvider) Key(kid string) (JWK, error) {
+	return p.set.Key(kid)
+}
+
+func TestDecodeJSON_RequiredKeys(t *testing.T) {
+	timeFunc = time.Now
+	ks := &KeySet{Keys: testKeySet().Keys[:2]}
+	token, err := EncodeJSON("hello", ks.Keys)
+	require.NoError(t, err)
+	var doc jwsGeneral
+	require.NoError(t, json.Unmarshal(token, &doc))
+	withSignatures := func(signatures ...jwsSignature) []byte {
+		raw, err := json.Marshal(jwsGeneral{Payload: doc.Payload, Signatures: signatures})
+		require.NoError(t, err)
+		return raw
+	}
+
+	var data string
+	require.NoError(t, DecodeJSON(token, &data, WithKeySet(ks)))
+
+	// Без WithRequiredKeys проверяются только имеющиеся подписи
+	stripped := withSignatures(doc.Signatures[0])
+	require.NoError(t, DecodeJSON(stripped, &data, WithKeySet(ks)))
+	require.NoError(t, DecodeJSON(stripped, &data, WithKeySet(ks), WithSignaturePolicy(RequireAny)))
+	require.ErrorIs(t, DecodeJSON(stripped, &data, WithKeySet(ks), WithRequiredKeys("hmac", "ec")), ErrSignatureInvalid)
+
+	// Ключи, подпись которыми обязательна, задаются явно
+	require.NoError(t, DecodeJSON(stripped, &data, WithKeySet(ks), WithRequiredKeys("hmac")))
+	require.ErrorIs(t, DecodeJSON(stripped, &data, WithKeySet(ks), WithRequiredKeys("ec")), ErrSignatureInvalid)
+	require.ErrorIs(t, DecodeJSON(stripped, &data, WithKeySet(ks), WithSignaturePolicy(RequireAny), WithRequiredKeys("hmac", "ec")), ErrSignatureInvalid)
+
+	// Набор может содержать ключи, которыми токен не подписан, и может быть неперечислимым
+	require.NoError(t, DecodeJSON(stripped, &data, WithKeySet(testKeySet()), WithRequiredKeys("hmac")))
+	provider := keyProvider{set: ks}
+	require.NoError(t, DecodeJSON(token, &data, WithKeySet(provider)))
+	require.NoError(t, DecodeJSON(token, &data, WithKeySet(provider), WithRequiredKeys("hmac", "ec")))
+	require.ErrorIs(t, DecodeJSON(stripped, &data, WithKeySet(provider), WithRequiredKeys("hmac", "ec")), ErrSignatureInvalid)
+
+	// Повтор подписи одним ключом не засчитывается за другие ключи
+	repeated := withSignatures(doc.Signatures[0], doc.Signatures[0])
+	require.ErrorIs(t, DecodeJSON(repeated, &data, WithKeySet(ks)), ErrInvalidToken)
+	require.ErrorIs(t, DecodeJSON(repeated, &data, WithKeySet(ks), WithSignaturePolicy(RequireAny)), ErrInvalidToken)
+
+	// То же, если kid вынесен в незащищенный заголовок
+	var protected bytes.Buffer
+	require.NoError(t, writeSegment(&protected, Header{Alg: HS256, Typ: tokenType}))
+	unprotected := jwsSignature{Protected: protected.String(), Header: &jwsUnprotected{Kid: "hmac"}, Signature: doc.Signatures[0].Signature}
+	repeated = withSignatures(doc.Signatures[0], unprotected)
+	require.ErrorIs(t, DecodeJSON(repeated, &data, WithKeySet(ks), WithSignaturePolicy(RequireAny)), ErrInvalidToken)
+}
+
+func TestDecodeJSON_Errors(t *testing.T) {
+	timeFunc = time.Now
+	ks := testKeySet()
+	var data interface{}
+	for _, tc := range []struct {
+		Name  string
+		Token string
+		Err   error
+	}{
+		{Name: "not json", Token: `eyJ9.e30.e30`, Err: ErrInvalidToken},
+		{Name: "no signatures", Token: `{"payload":"e30","signatures":[]}`, Err: ErrInvalidToken},
+		{Name: "both forms", Token: `{"payload":"e30","signatures":[{}],"signature":"AA"}`, Err: ErrInvalidToken},
+		{Name: "unsecured", Token: `{"payload":"e30","protected":"eyJhbGciOiJub25lIn0","signature":""}`, Err: ErrInvalidSignMethod},
+		{Name: "unknown key", Token: `{"payload":"e30","protected":"eyJhbGciOiJIUzI1NiJ9","header":{"kid":"x"},"signature":""}`, Err: ErrKeyNotFound},
+	} {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			require.ErrorIs(t, DecodeJSON([]byte(tc.Token), &data, WithKeySet(ks)), tc.Err)
+		})
+	}
+	require.ErrorIs(t, DecodeJSON([]byte(`{}`), &data), ErrConfigurationMalformed)
+}
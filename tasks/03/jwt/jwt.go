@@ -1,7 +1,12 @@
 package jwt
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -9,7 +14,15 @@ type SignMethod string
 
 const (
 	HS256 SignMethod = "HS256"
+	HS384 SignMethod = "HS384"
 	HS512 SignMethod = "HS512"
+	RS256 SignMethod = "RS256"
+	RS384 SignMethod = "RS384"
+	RS512 SignMethod = "RS512"
+	PS256 SignMethod = "PS256"
+	ES256 SignMethod = "ES256"
+	ES384 SignMethod = "ES384"
+	EdDSA SignMethod = "EdDSA"
 )
 
 var (
@@ -21,15 +34,261 @@ var (
 	ErrInvalidToken           = errors.New("invalid token")
 )
 
+const tokenType = "JWT"
+
+var (
+	encoding  = base64.RawURLEncoding.Strict()
+	separator = []byte{'.'}
+)
+
+// Header - заголовок подписанного токена
+type Header struct {
+	Alg SignMethod `json:"alg"`
+	Typ string     `json:"typ"`
+	Kid string     `json:"kid,omitempty"`
+	Cty string     `json:"cty,omitempty"`
+}
+
+type payload struct {
+	Data interface{} `json:"d"`
+	Claims
+}
+
+// RawClaims - содержимое токена с еще не декодированными пользовательскими данными
+type RawClaims struct {
+	Data json.RawMessage `json:"d"`
+	Claims
+}
+
 func Encode(data interface{}, opts ...Option) ([]byte, error) {
-	// TODO: implement me
-	return nil, nil
+	cfg := newConfig(opts)
+	if cfg.KeySet != nil && !cfg.hasKey() {
+		k, err := cfg.KeySet.Key(cfg.KeyID)
+		if err != nil {
+			return nil, err
+		}
+		alg := cfg.SignMethod
+		if alg == "" {
+			alg = k.Algorithm
+		}
+		if cfg, err = cfg.withJWK(k, alg); err != nil {
+			return nil, err
+		}
+	}
+	s, err := newSigner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := cfg.claims()
+	if err != nil {
+		return nil, err
+	}
+
+	var token bytes.Buffer
+	if err := writeSegment(&token, Header{Alg: cfg.SignMethod, Typ: tokenType, Kid: cfg.KeyID}); err != nil {
+		return nil, err
+	}
+	token.Write(separator)
+	raw, err := cfg.marshalPayload(data, claims)
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(&token, raw)
+
+	signature, err := s.Sign(token.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	token.Write(separator)
+	writeBase64(&token, signature)
+	return token.Bytes(), nil
 }
 
 func Decode(token []byte, data interface{}, opts ...Option) error {
-	// TODO: implement me
+	cfg := newConfig(opts)
+	if err := cfg.Limits.checkToken(token); err != nil {
+		return err
+	}
+	parts, err := splitToken(token)
+	if err != nil {
+		return err
+	}
+
+	var h Header
+	if err := cfg.readHeader(parts[0], &h); err != nil {
+		return err
+	}
+	var p RawClaims
+	switch {
+	case cfg.KeyFunc != nil && cfg.KeySet != nil:
+		return fmt.Errorf("%w: both key func and key set are set", ErrConfigurationMalformed)
+	case cfg.KeyFunc != nil:
+		if p, err = readPayload(cfg, parts[1]); err != nil {
+			return err
+		}
+		k, err := cfg.KeyFunc(h, p)
+		if err != nil {
+			return err
+		}
+		if cfg, err = cfg.withJWK(k, h.Alg); err != nil {
+			return err
+		}
+	case cfg.KeySet != nil:
+		k, err := cfg.KeySet.Key(h.Kid)
+		if err != nil {
+			return err
+		}
+		if cfg, err = cfg.withJWK(k, h.Alg); err != nil {
+			return err
+		}
+	}
+	s, err := newSigner(cfg)
+	if err != nil {
+		return err
+	}
+	if h.Alg != cfg.SignMethod {
+		return fmt.Errorf("%w: token is signed with %q", ErrSignMethodMismatched, h.Alg)
+	}
+
+	signature, err := decodeBase64(parts[2])
+	if err != nil {
+		return err
+	}
+	signed := token[:len(parts[0])+len(separator)+len(parts[1])]
+	if err := s.Verify(signed, signature); err != nil {
+		return err
+	}
+
+	if cfg.KeyFunc == nil {
+		if p, err = readPayload(cfg, parts[1]); err != nil {
+			return err
+		}
+	}
+	if err := cfg.validate(&p.Claims); err != nil {
+		return err
+	}
+	if err := cfg.unmarshalData(p.Data, data); err != nil {
+		return err
+	}
+	if cfg.Header != nil {
+		*cfg.Header = h
+	}
+	if cfg.Claims != nil {
+		*cfg.Claims = p.Claims
+	}
 	return nil
 }
 
+// ParseUnverified разбирает заголовок и содержимое токена без проверки подписи.
+// Результат можно использовать только для выбора ключа, но не для принятия решений
+func ParseUnverified(token []byte) (Header, RawClaims, error) {
+	cfg := newConfig(nil)
+	var h Header
+	if err := cfg.Limits.checkToken(token); err != nil {
+		return h, RawClaims{}, err
+	}
+	parts, err := splitToken(token)
+	if err != nil {
+		return h, RawClaims{}, err
+	}
+	if err := cfg.readHeader(parts[0], &h); err != nil {
+		return h, RawClaims{}, err
+	}
+	p, err := readPayload(cfg, parts[1])
+	return h, p, err
+}
+
+func splitToken(token []byte) ([][]byte, error) {
+	parts := bytes.Split(token, separator)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected 3 parts, got %d", ErrInvalidToken, len(parts))
+	}
+	return parts, nil
+}
+
+// unmarshalData декодирует пользовательские данные с учетом режима чисел
+func (c *config) unmarshalData(raw []byte, data interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if c.NumberMode == NumberJSON {
+		decoder.UseNumber()
+	}
+	if err := decoder.Decode(data); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+// writeSegment записывает в `b` значение `v`, закодированное в json и base64
+func writeSegment(b *bytes.Buffer, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	writeBase64(b, raw)
+	return nil
+}
+
+func writeBase64(b *bytes.Buffer, raw []byte) {
+	encoder := base64.NewEncoder(encoding, b)
+	_, _ = encoder.Write(raw)
+	_ = encoder.Close()
+}
+
+func readPayload(cfg *config, segment []byte) (RawClaims, error) {
+	raw, err := decodeBase64(segment)
+	if err != nil {
+		return RawClaims{}, err
+	}
+	return cfg.unmarshalPayload(raw)
+}
+
+// readHeader декодирует заголовок токена с проверкой лимитов. Токены без подписи
+// (`alg: none`) отвергаются сразу
+func (c *config) readHeader(segment []byte, h interface{}) error {
+	if err := c.Limits.checkHeader(segment); err != nil {
+		return err
+	}
+	raw, err := decodeBase64(segment)
+	if err != nil {
+		return err
+	}
+	if err := c.Limits.checkDepth(raw); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, h); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if jws, ok := h.(*Header); ok && strings.EqualFold(string(jws.Alg), "none") {
+		return fmt.Errorf("%w: unsecured token", ErrInvalidSignMethod)
+	}
+	return nil
+}
+
+// readSegment декодирует часть токена `segment` в `v`
+func readSegment(segment []byte, v interface{}) error {
+	raw, err := decodeBase64(segment)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+// decodeBase64 декодирует часть токена. Переводы строк, которые пропускает
+// base64.Encoding, запрещены: иначе у токена появляются альтернативные представления
+func decodeBase64(segment []byte) ([]byte, error) {
+	if bytes.ContainsAny(segment, "\r\n") {
+		return nil, fmt.Errorf("%w: line breaks in base64", ErrInvalidToken)
+	}
+	raw := make([]byte, encoding.DecodedLen(len(segment)))
+	n, err := encoding.Decode(raw, segment)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return raw[:n], nil
+}
+
 // To mock time in tests
 var timeFunc = time.Now
@@ -0,0 +1,188 @@
+package jwt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Расширения файлов, которые KeyDir считает ключами
+var keyFileExts = map[string]bool{".pem": true, ".der": true, ".key": true, ".pub": true, ".crt": true}
+
+// KeyDir загружает ключи из файлов каталога и перечитывает их при изменении.
+// Идентификатор ключа - имя файла без расширения, скрытые файлы и файлы
+// с другими расширениями пропускаются
+type KeyDir struct {
+	dir      string
+	password []byte
+
+	mutex sync.RWMutex
+	set   *KeySet
+	state map[string]keyFileState
+	// Ошибка последней перезагрузки
+	lastErr error
+
+	reloadMutex sync.Mutex
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+type keyFileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// NewKeyDir загружает ключи из каталога `dir`, зашифрованные ключи расшифровываются
+// паролем `password`. Если `refresh` больше нуля, каталог проверяется на изменения
+// с этим интервалом до вызова Close
+func NewKeyDir(dir string, password []byte, refresh time.Duration) (*KeyDir, error) {
+	d := &KeyDir{
+		dir:      dir,
+		password: password,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if err := d.Reload(); err != nil {
+		return nil, err
+	}
+	if refresh > 0 {
+		go d.refreshLoop(refresh)
+	} else {
+		close(d.done)
+	}
+	return d, nil
+}
+
+func (d *KeyDir) refreshLoop(refresh time.Duration) {
+	defer close(d.done)
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = d.Reload()
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Close останавливает отслеживание изменений
+func (d *KeyDir) Close() {
+	d.mutex.Lock()
+	select {
+	case <-d.stop:
+	default:
+		close(d.stop)
+	}
+	d.mutex.Unlock()
+	<-d.done
+}
+
+func (d *KeyDir) Key(kid string) (JWK, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.set.Key(kid)
+}
+
+// KeySet возвращает текущий набор ключей
+func (d *KeyDir) KeySet() *KeySet {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.set
+}
+
+// LastError возвращает ошибку последней перезагрузки, в том числе фоновой, или nil,
+// если она прошла успешно
+func (d *KeyDir) LastError() error {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.lastErr
+}
+
+// Reload перечитывает ключи, если файлы в каталоге изменились. При ошибке
+// остается предыдущий набор ключей
+func (d *KeyDir) Reload() error {
+	d.reloadMutex.Lock()
+	defer d.reloadMutex.Unlock()
+
+	err := d.reload()
+	d.mutex.Lock()
+	d.lastErr = err
+	d.mutex.Unlock()
+	return err
+}
+
+func (d *KeyDir) reload() error {
+	state, err := d.scan()
+	if err != nil {
+		return err
+	}
+	d.mutex.RLock()
+	changed := !sameKeyFiles(d.state, state)
+	d.mutex.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	names := make([]string, 0, len(state))
+	for name := range state {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	set := &KeySet{Keys: make([]JWK, 0, len(names))}
+	seen := make(map[string]string, len(names))
+	for _, name := range names {
+		k, err := LoadKey(filepath.Join(d.dir, name), d.password)
+		if err != nil {
+			return err
+		}
+		if other, ok := seen[k.KeyID]; ok {
+			return fmt.Errorf("%w: files %s and %s have the same key id", ErrInvalidKey, other, name)
+		}
+		seen[k.KeyID] = name
+		set.Keys = append(set.Keys, k)
+	}
+	d.mutex.Lock()
+	d.set, d.state = set, state
+	d.mutex.Unlock()
+	return nil
+}
+
+func (d *KeyDir) scan() (map[string]keyFileState, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	state := make(map[string]keyFileState, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !keyFileExts[filepath.Ext(name)] {
+			continue
+		}
+		// Stat идет по символическим ссылкам: так ротируются ключи, смонтированные из секретов
+		info, err := os.Stat(filepath.Join(d.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		state[name] = keyFileState{size: info.Size(), modTime: info.ModTime()}
+	}
+	return state, nil
+}
+
+func sameKeyFiles(a, b map[string]keyFileState) bool {
+	if a == nil || len(a) != len(b) {
+		return false
+	}
+	for name, s := range a {
+		if other, ok := b[name]; !ok || !other.modTime.Equal(s.modTime) || other.size != s.size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrKeyPassword = errors.New("invalid key password")
+
+// ParsePEM разбирает первый ключ в PEM: PKCS#1, PKCS#8, SEC 1, SPKI или сертификат X.509.
+// Зашифрованные ключи (PKCS#8 PBES2 и устаревший формат OpenSSL) расшифровываются паролем `password`
+func ParsePEM(data, password []byte) (JWK, error) {
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "EC PARAMETERS" {
+			continue
+		}
+		der := block.Bytes
+		// Устаревший формат шифрования OpenSSL (заголовки Proc-Type и DEK-Info)
+		if x509.IsEncryptedPEMBlock(block) {
+			if len(password) == 0 {
+				return JWK{}, fmt.Errorf("%w: key is encrypted", ErrKeyPassword)
+			}
+			var err error
+			if der, err = x509.DecryptPEMBlock(block, password); err != nil {
+				return JWK{}, fmt.Errorf("%w: %v", ErrKeyPassword, err)
+			}
+		}
+		key, err := parsePEMBlock(block.Type, der, password)
+		if err != nil {
+			return JWK{}, err
+		}
+		return newFileJWK(key)
+	}
+	return JWK{}, fmt.Errorf("%w: no PEM data", ErrInvalidKey)
+}
+
+func parsePEMBlock(blockType string, der, password []byte) (interface{}, error) {
+	var key interface{}
+	var err error
+	switch blockType {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(der)
+	case "ENCRYPTED PRIVATE KEY":
+		if len(password) == 0 {
+			return nil, fmt.Errorf("%w: key is encrypted", ErrKeyPassword)
+		}
+		if der, err = decryptPKCS8(der, password); err != nil {
+			return nil, err
+		}
+		if key, err = x509.ParsePKCS8PrivateKey(der); err != nil {
+			// Неверный пароль может дать корректное дополнение, но не корректный ключ
+			return nil, fmt.Errorf("%w: %v", ErrKeyPassword, err)
+		}
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(der)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(der)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(der)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(der)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(der); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported PEM block %q", ErrInvalidKey, blockType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	return key, nil
+}
+
+// ParseDER разбирает ключ в DER, формат определяется автоматически
+func ParseDER(der []byte) (JWK, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return newFileJWK(key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return newFileJWK(key)
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return newFileJWK(key)
+	}
+	if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return newFileJWK(key)
+	}
+	if key, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return newFileJWK(key)
+	}
+	return JWK{}, fmt.Errorf("%w: unknown DER format", ErrInvalidKey)
+}
+
+// ParseKey разбирает ключ в PEM или DER
+func ParseKey(data, password []byte) (JWK, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		return ParsePEM(data, password)
+	}
+	return ParseDER(data)
+}
+
+// LoadKey загружает ключ из файла в PEM или DER. Идентификатором ключа
+// становится имя файла без расширения
+func LoadKey(path string, password []byte) (JWK, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return JWK{}, err
+	}
+	k, err := ParseKey(data, password)
+	if err != nil {
+		return JWK{}, fmt.Errorf("%s: %w", path, err)
+	}
+	name := filepath.Base(path)
+	k.KeyID = strings.TrimSuffix(name, filepath.Ext(name))
+	return k, nil
+}
+
+// KeyFromEnv загружает ключ из переменной окружения `name`. Значение - PEM
+// или PEM/DER, закодированный в base64
+func KeyFromEnv(name string, password []byte) (JWK, error) {
+	value, err := envBase64(name)
+	if err != nil {
+		return JWK{}, err
+	}
+	return ParseKey(value, password)
+}
+
+// SecretFromEnv загружает HMAC ключ, закодированный в base64, из переменной окружения `name`
+func SecretFromEnv(name string) ([]byte, error) {
+	return envBase64(name)
+}
+
+func envBase64(name string) ([]byte, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return nil, fmt.Errorf("%w: environment variable %s is not set", ErrInvalidKey, name)
+	}
+	if strings.HasPrefix(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if raw, err := enc.DecodeString(value); err == nil {
+			return raw, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: environment variable %s is not base64", ErrInvalidKey, name)
+}
+
+// newFileJWK оборачивает ключ из файла в JWK. Метод подписи заполняется,
+// только если он однозначно определяется ключом
+func newFileJWK(key interface{}) (JWK, error) {
+	k := JWK{Key: key}
+	switch key := key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		// RSA ключ подходит для RS* и PS*, метод нужно задать явно
+		return k, nil
+	case *ecdsa.PrivateKey:
+		k.Algorithm = ecdsaMethod(key.Curve)
+	case *ecdsa.PublicKey:
+		k.Algorithm = ecdsaMethod(key.Curve)
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		k.Algorithm = EdDSA
+	default:
+		return JWK{}, fmt.Errorf("%w: unsupported key type %T", ErrInvalidKey, key)
+	}
+	if k.Algorithm == "" {
+		return JWK{}, fmt.Errorf("%w: unsupported curve", ErrInvalidKey)
+	}
+	return k, nil
+}
+
+func ecdsaMethod(curve elliptic.Curve) SignMethod {
+	switch curve {
+	case elliptic.P256():
+		return ES256
+	case elliptic.P384():
+		return ES384
+	default:
+		return ""
+	}
+}
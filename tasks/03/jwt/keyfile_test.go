@@ -0,0 +1,236 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+var keyPassword = []byte("secret")
+
+func TestLoadKey_Encrypted(t *testing.T) {
+	timeFunc = time.Now
+	for _, tc := range []struct {
+		Name   string
+		Method SignMethod
+	}{
+		{Name: "ec-pbes2-sha256", Method: ES256},
+		{Name: "ed-pbes2-sha1", Method: EdDSA},
+		{Name: "rsa-legacy", Method: RS256},
+	} {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			path := filepath.Join("testdata", "keys", tc.Name)
+			private, err := LoadKey(path+".pem", keyPassword)
+			require.NoError(t, err)
+			require.Equal(t, tc.Name, private.KeyID)
+
+			publicPath := path + ".pub"
+			if tc.Method == RS256 {
+				publicPath = path + ".der"
+			}
+			public, err := LoadKey(publicPath, nil)
+			require.NoError(t, err)
+
+			token, err := Encode("hello", WithJWK(private), WithSignMethod(tc.Method))
+			require.NoError(t, err)
+			var data string
+			require.NoError(t, Decode(token, &data, WithJWK(public), WithSignMethod(tc.Method)))
+			require.Equal(t, "hello", data)
+
+			_, err = LoadKey(path+".pem", []byte("wrong"))
+			require.ErrorIs(t, err, ErrKeyPassword)
+			_, err = LoadKey(path+".pem", nil)
+			require.ErrorIs(t, err, ErrKeyPassword)
+		})
+	}
+}
+
+func TestPBES2Key_Iterations(t *testing.T) {
+	kdf := func(iterations int) pkix.AlgorithmIdentifier {
+		raw, err := asn1.Marshal(pbkdf2Params{Salt: []byte("salt"), Iterations: iterations})
+		require.NoError(t, err)
+		return pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: raw}}
+	}
+
+	// RFC 6070, тест 2
+	key, err := pbes2Key(kdf(2), []byte("password"), 20)
+	require.NoError(t, err)
+	require.Equal(t, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957", hex.EncodeToString(key))
+
+	_, err = pbes2Key(kdf(0), []byte("password"), 16)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = pbes2Key(kdf(maxPBKDF2Iterations+1), []byte("password"), 16)
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestParseKey_Formats(t *testing.T) {
+	pkcs1 := x509.MarshalPKCS1PrivateKey(testRSAKey)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(testEd25519Key)
+	require.NoError(t, err)
+	sec1, err := x509.MarshalECPrivateKey(testP384Key)
+	require.NoError(t, err)
+	spki, err := x509.MarshalPKIXPublicKey(&testP256Key.PublicKey)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		Name      string
+		Block     string
+		DER       []byte
+		Algorithm SignMethod
+		Check     func(t *testing.T, key interface{})
+	}{
+		{Name: "pkcs1", Block: "RSA PRIVATE KEY", DER: pkcs1, Check: func(t *testing.T, key interface{}) {
+			require.True(t, key.(*rsa.PrivateKey).Equal(testRSAKey))
+		}},
+		{Name: "pkcs1 public", Block: "RSA PUBLIC KEY", DER: x509.MarshalPKCS1PublicKey(&testRSAKey.PublicKey), Check: func(t *testing.T, key interface{}) {
+			require.True(t, key.(*rsa.PublicKey).Equal(&testRSAKey.PublicKey))
+		}},
+		{Name: "pkcs8", Block: "PRIVATE KEY", DER: pkcs8, Algorithm: EdDSA, Check: func(t *testing.T, key interface{}) {
+			require.True(t, key.(ed25519.PrivateKey).Equal(testEd25519Key))
+		}},
+		{Name: "sec1", Block: "EC PRIVATE KEY", DER: sec1, Algorithm: ES384, Check: func(t *testing.T, key interface{}) {
+			require.True(t, key.(*ecdsa.PrivateKey).Equal(testP384Key))
+		}},
+		{Name: "spki", Block: "PUBLIC KEY", DER: spki, Algorithm: ES256, Check: func(t *testing.T, key interface{}) {
+			require.True(t, key.(*ecdsa.PublicKey).Equal(&testP256Key.PublicKey))
+		}},
+	} {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			for _, data := range [][]byte{tc.DER, pem.EncodeToMemory(&pem.Block{Type: tc.Block, Bytes: tc.DER})} {
+				k, err := ParseKey(data, nil)
+				require.NoError(t, err)
+				require.Equal(t, tc.Algorithm, k.Algorithm)
+				tc.Check(t, k.Key)
+			}
+		})
+	}
+
+	_, err = ParseKey([]byte("garbage"), nil)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = ParsePEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: pkcs1}), nil)
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestKeyFromEnv(t *testing.T) {
+	der, err := x509.MarshalPKCS8PrivateKey(testP256Key)
+	require.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	for name, value := range map[string]string{
+		"pem":        string(data),
+		"base64 pem": base64.StdEncoding.EncodeToString(data),
+		"base64 der": base64.RawURLEncoding.EncodeToString(der),
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("JWT_TEST_KEY", value)
+			k, err := KeyFromEnv("JWT_TEST_KEY", nil)
+			require.NoError(t, err)
+			require.True(t, k.Key.(*ecdsa.PrivateKey).Equal(testP256Key))
+		})
+	}
+
+	t.Setenv("JWT_TEST_KEY", "")
+	_, err = KeyFromEnv("JWT_TEST_KEY", nil)
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	t.Setenv("JWT_TEST_SECRET", base64.StdEncoding.EncodeToString([]byte("secret")))
+	secret, err := SecretFromEnv("JWT_TEST_SECRET")
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret"), secret)
+	t.Setenv("JWT_TEST_SECRET", "not base64!")
+	_, err = SecretFromEnv("JWT_TEST_SECRET")
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func writeKeyFile(t *testing.T, path string, key interface{}) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+}
+
+func TestKeyDir(t *testing.T) {
+	timeFunc = time.Now
+	dir := t.TempDir()
+	writeKeyFile(t, filepath.Join(dir, "k1.pem"), testP256Key)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("not a key"), 0o600))
+
+	d, err := NewKeyDir(dir, nil, 0)
+	require.NoError(t, err)
+	defer d.Close()
+	require.Len(t, d.KeySet().Keys, 1)
+
+	token, err := Encode("hello", WithKeySet(d), WithKeyID("k1"))
+	require.NoError(t, err)
+	var data string
+	require.NoError(t, Decode(token, &data, WithKeySet(d)))
+
+	// Ротация: новый ключ добавлен, старый удален
+	writeKeyFile(t, filepath.Join(dir, "k2.pem"), testEd25519Key)
+	require.NoError(t, os.Remove(filepath.Join(dir, "k1.pem")))
+	require.NoError(t, d.Reload())
+	require.ErrorIs(t, Decode(token, &data, WithKeySet(d)), ErrKeyNotFound)
+	_, err = d.Key("k2")
+	require.NoError(t, err)
+
+	// Некорректный файл не ломает уже загруженный набор
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "k3.pem"), []byte("broken"), 0o600))
+	require.ErrorIs(t, d.Reload(), ErrInvalidKey)
+	require.ErrorIs(t, d.LastError(), ErrInvalidKey)
+	_, err = d.Key("k2")
+	require.NoError(t, err)
+
+	// Ключи с одинаковым идентификатором
+	require.NoError(t, os.Remove(filepath.Join(dir, "k3.pem")))
+	writeKeyFile(t, filepath.Join(dir, "k2.key"), testP256Key)
+	require.ErrorIs(t, d.Reload(), ErrInvalidKey)
+
+	_, err = NewKeyDir(filepath.Join(dir, "missing"), nil, 0)
+	require.Error(t, err)
+}
+
+func TestKeyDir_Watch(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	timeFunc = time.Now
+	dir := t.TempDir()
+	writeKeyFile(t, filepath.Join(dir, "k1.pem"), testP256Key)
+
+	d, err := NewKeyDir(dir, nil, 10*time.Millisecond)
+	require.NoError(t, err)
+	defer d.Close()
+
+	writeKeyFile(t, filepath.Join(dir, "k2.pem"), testEd25519Key)
+	require.Eventually(t, func() bool {
+		_, err := d.Key("k2")
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, d.LastError())
+
+	// Ошибка фоновой перезагрузки видна через LastError, пока файл не исправят
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "k3.pem"), []byte("broken"), 0o600))
+	require.Eventually(t, func() bool {
+		return errors.Is(d.LastError(), ErrInvalidKey)
+	}, time.Second, 5*time.Millisecond)
+	_, err = d.Key("k2")
+	require.NoError(t, err)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "k3.pem")))
+	require.Eventually(t, func() bool {
+		return d.LastError() == nil
+	}, time.Second, 5*time.Millisecond)
+}
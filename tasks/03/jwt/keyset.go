@@ -0,0 +1,236 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrKeyNotFound = errors.New("key not found")
+
+// KeyProvider выбирает ключ по его идентификатору (заголовок `kid`)
+type KeyProvider interface {
+	Key(kid string) (JWK, error)
+}
+
+// KeySet - набор ключей в формате JWKS
+type KeySet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ParseKeySet разбирает набор ключей в формате JWKS
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var ks KeySet
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, err
+	}
+	return &ks, nil
+}
+
+// Key возвращает ключ с идентификатором `kid`. Если `kid` пуст,
+// а в наборе ровно один ключ, возвращается он
+func (ks *KeySet) Key(kid string) (JWK, error) {
+	if kid == "" && len(ks.Keys) == 1 {
+		return ks.Keys[0], nil
+	}
+	for _, k := range ks.Keys {
+		if kid != "" && k.KeyID == kid {
+			return k, nil
+		}
+	}
+	return JWK{}, fmt.Errorf("%w: %q", ErrKeyNotFound, kid)
+}
+
+// Public возвращает набор открытых ключей для публикации, HMAC ключи пропускаются
+func (ks *KeySet) Public() *KeySet {
+	public := &KeySet{Keys: []JWK{}}
+	for _, k := range ks.Keys {
+		if pk, ok := k.Public(); ok {
+			public.Keys = append(public.Keys, pk)
+		}
+	}
+	return public
+}
+
+// Максимальный размер загружаемого набора ключей
+const maxRemoteKeySetSize = 1 << 20
+
+// Минимальный интервал между загрузками набора ключей при поиске неизвестного `kid`
+const minRemoteRefresh = 10 * time.Second
+
+// Время на загрузку набора ключей при поиске неизвестного `kid`. Переменная, чтобы
+// уменьшать его в тестах
+var remoteFetchTimeout = 10 * time.Second
+
+// RemoteKeySet загружает набор ключей по HTTP и кэширует его. При запросе
+// неизвестного ключа набор загружается заново (не чаще, чем раз в minRemoteRefresh,
+// в том числе после неудачной загрузки), кроме того, набор периодически обновляется в фоне
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mutex sync.RWMutex
+	set   *KeySet
+	etag  string
+	// Время и результат последней попытки загрузки
+	attempted time.Time
+	fetchErr  error
+
+	fetchMutex sync.Mutex
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewRemoteKeySet создает набор ключей, загружаемый с адреса `url`. Если `refresh`
+// больше нуля, набор обновляется в фоне с этим интервалом до вызова Close
+func NewRemoteKeySet(url string, client *http.Client, refresh time.Duration) *RemoteKeySet {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	r := &RemoteKeySet{
+		url:    url,
+		client: client,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if refresh > 0 {
+		go r.refreshLoop(refresh)
+	} else {
+		close(r.done)
+	}
+	return r
+}
+
+func (r *RemoteKeySet) refreshLoop(refresh time.Duration) {
+	defer close(r.done)
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), refresh)
+			_ = r.Refresh(ctx)
+			cancel()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Close останавливает фоновое обновление
+func (r *RemoteKeySet) Close() {
+	r.mutex.Lock()
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+	}
+	r.mutex.Unlock()
+	<-r.done
+}
+
+func (r *RemoteKeySet) Key(kid string) (JWK, error) {
+	r.mutex.RLock()
+	set := r.set
+	r.mutex.RUnlock()
+	if set != nil {
+		if k, err := set.Key(kid); err == nil {
+			return k, nil
+		}
+	}
+
+	// Ключа нет в кэше: возможно, ключи ротировались
+	ctx, cancel := context.WithTimeout(context.Background(), remoteFetchTimeout)
+	defer cancel()
+	if err := r.refreshIfStale(ctx); err != nil {
+		return JWK{}, err
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if r.set == nil {
+		return JWK{}, fmt.Errorf("%w: key set is not loaded", ErrKeyNotFound)
+	}
+	return r.set.Key(kid)
+}
+
+// refreshIfStale загружает набор ключей, если с последней попытки прошло не меньше
+// minRemoteRefresh, иначе возвращает ее результат. Время проверяется под fetchMutex:
+// пока вызов ждал, набор мог загрузить другой
+func (r *RemoteKeySet) refreshIfStale(ctx context.Context) error {
+	r.fetchMutex.Lock()
+	defer r.fetchMutex.Unlock()
+	r.mutex.RLock()
+	attempted, fetchErr := r.attempted, r.fetchErr
+	r.mutex.RUnlock()
+	if !attempted.IsZero() && timeFunc().Sub(attempted) < minRemoteRefresh {
+		return fetchErr
+	}
+	return r.fetch(ctx)
+}
+
+// Refresh загружает набор ключей. Если набор не изменился (по ETag), кэш не меняется
+func (r *RemoteKeySet) Refresh(ctx context.Context) error {
+	r.fetchMutex.Lock()
+	defer r.fetchMutex.Unlock()
+	return r.fetch(ctx)
+}
+
+// fetch загружает набор и запоминает результат попытки, вызывается под fetchMutex
+func (r *RemoteKeySet) fetch(ctx context.Context) error {
+	set, etag, err := r.download(ctx)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.attempted, r.fetchErr = timeFunc(), err
+	if set != nil {
+		r.set, r.etag = set, etag
+	}
+	return err
+}
+
+// download запрашивает набор ключей, если набор не изменился, возвращает nil
+func (r *RemoteKeySet) download(ctx context.Context) (*KeySet, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	r.mutex.RLock()
+	if r.etag != "" {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+	r.mutex.RUnlock()
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, "", nil
+	case http.StatusOK:
+	default:
+		return nil, "", fmt.Errorf("fetch key set: unexpected status %d", resp.StatusCode)
+	}
+
+	// Лишний байт отличает набор ровно на пределе от слишком большого
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteKeySetSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxRemoteKeySetSize {
+		return nil, "", fmt.Errorf("fetch key set: response is larger than %d bytes", maxRemoteKeySetSize)
+	}
+	set, err := ParseKeySet(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return set, resp.Header.Get("ETag"), nil
+}
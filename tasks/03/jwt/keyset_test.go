@@ -0,0 +1,338 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestJWK_RoundTrip(t *testing.T) {
+	timeFunc = time.Now
+	for _, tc := range asymmetricTestCases {
+		tc := tc
+		t.Run(string(tc.Method), func(t *testing.T) {
+			raw, err := json.Marshal(JWK{KeyID: "k", Algorithm: tc.Method, Key: tc.Key})
+			require.NoError(t, err)
+
+			var private JWK
+			require.NoError(t, json.Unmarshal(raw, &private))
+			require.Equal(t, "k", private.KeyID)
+			require.Equal(t, tc.Method, private.Algorithm)
+
+			public, ok := private.Public()
+			require.True(t, ok)
+			raw, err = json.Marshal(public)
+			require.NoError(t, err)
+			require.NotContains(t, string(raw), `"d"`)
+
+			var parsed JWK
+			require.NoError(t, json.Unmarshal(raw, &parsed))
+
+			token, err := Encode("hello", WithSignMethod(tc.Method), WithPrivateKey(private.Key.(crypto.Signer)))
+			require.NoError(t, err)
+			var data string
+			require.NoError(t, Decode(token, &data, WithSignMethod(tc.Method), WithPublicKey(parsed.Key)))
+			require.Equal(t, "hello", data)
+		})
+	}
+}
+
+func TestJWK_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		`{"kty":"oct"}`,
+		`{"kty":"RSA","n":"AQAB"}`,
+		`{"kty":"EC","crv":"P-521","x":"AA","y":"AA"}`,
+		`{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}`,
+		`{"kty":"OKP","crv":"Ed25519","x":"AA"}`,
+		`{"kty":"unknown"}`,
+	} {
+		var k JWK
+		require.ErrorIs(t, json.Unmarshal([]byte(raw), &k), ErrInvalidKey, raw)
+	}
+}
+
+func TestParseKeySet_RFC7517(t *testing.T) {
+	// RFC 7517, приложение A.1
+	ks, err := ParseKeySet([]byte(`{"keys":[{"kty":"EC","crv":"P-256",
+		"x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4",
+		"y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM",
+		"use":"enc","kid":"1"}]}`))
+	require.NoError(t, err)
+	k, err := ks.Key("1")
+	require.NoError(t, err)
+	require.Equal(t, "enc", k.Use)
+
+	_, err = ks.Key("2")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func testKeySet() *KeySet {
+	return &KeySet{Keys: []JWK{
+		{KeyID: "hmac", Algorithm: HS256, Key: []byte("secret")},
+		{KeyID: "ec", Algorithm: ES256, Key: testP256Key},
+		{KeyID: "ed", Key: testEd25519Key},
+	}}
+}
+
+func TestKeySet(t *testing.T) {
+	timeFunc = time.Now
+	ks := testKeySet()
+
+	for _, kid := range []string{"hmac", "ec"} {
+		token, err := Encode("hello", WithKeySet(ks), WithKeyID(kid))
+		require.NoError(t, err)
+
+		var h Header
+		require.NoError(t, readSegment(token[:bytes.IndexByte(token, '.')], &h))
+		require.Equal(t, kid, h.Kid)
+
+		var data string
+		require.NoError(t, Decode(token, &data, WithKeySet(ks)))
+		require.Equal(t, "hello", data)
+	}
+
+	// Проверка открытым набором ключей
+	token, err := Encode("hello", WithKeySet(ks), WithKeyID("ec"))
+	require.NoError(t, err)
+	raw, err := json.Marshal(ks.Public())
+	require.NoError(t, err)
+	public, err := ParseKeySet(raw)
+	require.NoError(t, err)
+	require.Len(t, public.Keys, 2)
+	var data string
+	require.NoError(t, Decode(token, &data, WithKeySet(public)))
+
+	// Метод подписи не указан в ключе и должен быть задан явно
+	token, err = Encode("hello", WithKeySet(ks), WithKeyID("ed"), WithSignMethod(EdDSA))
+	require.NoError(t, err)
+	require.NoError(t, Decode(token, &data, WithKeySet(ks), WithSignMethod(EdDSA)))
+	require.ErrorIs(t, Decode(token, &data, WithKeySet(ks)), ErrInvalidSignMethod)
+}
+
+func TestKeySet_Errors(t *testing.T) {
+	timeFunc = time.Now
+	ks := testKeySet()
+
+	_, err := Encode("hello", WithKeySet(ks), WithKeyID("unknown"))
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = Encode("hello", WithKeySet(ks), WithKeyID("ec"), WithSignMethod(ES384))
+	require.ErrorIs(t, err, ErrSignMethodMismatched)
+
+	// Токен без kid, а в наборе несколько ключей
+	token, err := Encode("hello", WithSignMethod(HS256), WithKey([]byte("secret")))
+	require.NoError(t, err)
+	var data string
+	require.ErrorIs(t, Decode(token, &data, WithKeySet(ks)), ErrKeyNotFound)
+
+	// kid указывает на ключ для другого метода
+	token, err = Encode("hello", WithSignMethod(HS384), WithKey([]byte("secret")), WithKeyID("hmac"))
+	require.NoError(t, err)
+	require.ErrorIs(t, Decode(token, &data, WithKeySet(ks)), ErrSignMethodMismatched)
+}
+
+// jwksServer отдает набор ключей с поддержкой ETag и считает запросы
+type jwksServer struct {
+	mutex    sync.Mutex
+	set      *KeySet
+	etag     string
+	requests int
+}
+
+func (s *jwksServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests++
+	if req.Header.Get("If-None-Match") == s.etag {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+	rw.Header().Set("ETag", s.etag)
+	_ = json.NewEncoder(rw).Encode(s.set)
+}
+
+func (s *jwksServer) rotate(set *KeySet, etag string) {
+	s.mutex.Lock()
+	s.set, s.etag = set, etag
+	s.mutex.Unlock()
+}
+
+func (s *jwksServer) count() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	now := time.Now()
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	ks := testKeySet()
+	jwks := &jwksServer{}
+	jwks.rotate(&KeySet{Keys: ks.Keys[:1]}, `"v1"`)
+	server := httptest.NewServer(jwks)
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	remote := NewRemoteKeySet(server.URL, client, 0)
+	defer remote.Close()
+
+	k, err := remote.Key("hmac")
+	require.NoError(t, err)
+	require.Equal(t, HS256, k.Algorithm)
+	_, err = remote.Key("hmac")
+	require.NoError(t, err)
+	require.Equal(t, 1, jwks.count())
+
+	// Неизвестный ключ не загружается заново слишком часто
+	jwks.rotate(ks, `"v2"`)
+	_, err = remote.Key("ec")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.Equal(t, 1, jwks.count())
+
+	now = now.Add(minRemoteRefresh)
+	k, err = remote.Key("ec")
+	require.NoError(t, err)
+	require.Equal(t, ES256, k.Algorithm)
+	require.Equal(t, 2, jwks.count())
+
+	// Набор не изменился
+	require.NoError(t, remote.Refresh(context.Background()))
+	require.Equal(t, 3, jwks.count())
+	_, err = remote.Key("ec")
+	require.NoError(t, err)
+}
+
+func TestRemoteKeySet_Background(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	timeFunc = time.Now
+
+	ks := testKeySet()
+	jwks := &jwksServer{}
+	jwks.rotate(&KeySet{Keys: ks.Keys[:1]}, `"v1"`)
+	server := httptest.NewServer(jwks)
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	remote := NewRemoteKeySet(server.URL, client, 10*time.Millisecond)
+	defer remote.Close()
+
+	_, err := remote.Key("hmac")
+	require.NoError(t, err)
+
+	jwks.rotate(ks, `"v2"`)
+	require.Eventually(t, func() bool {
+		remote.mutex.RLock()
+		defer remote.mutex.RUnlock()
+		return remote.etag == `"v2"`
+	}, time.Second, 5*time.Millisecond)
+
+	_, err = remote.Key("ed")
+	require.NoError(t, err)
+}
+
+func TestRemoteKeySet_BadStatus(t *testing.T) {
+	now := time.Now()
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	var mutex sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		requests++
+		mutex.Unlock()
+		http.NotFound(rw, req)
+	}))
+	defer server.Close()
+
+	remote := NewRemoteKeySet(server.URL, server.Client(), 0)
+	defer remote.Close()
+	_, err := remote.Key("hmac")
+	require.Error(t, err)
+
+	// Неудачная загрузка не повторяется на каждый запрос
+	_, err = remote.Key("other")
+	require.Error(t, err)
+	mutex.Lock()
+	require.Equal(t, 1, requests)
+	mutex.Unlock()
+}
+
+func TestRemoteKeySet_TooLarge(t *testing.T) {
+	timeFunc = time.Now
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`{"keys":[],"padding":"`))
+		_, _ = rw.Write(bytes.Repeat([]byte("a"), maxRemoteKeySetSize))
+		_, _ = rw.Write([]byte(`"}`))
+	}))
+	defer server.Close()
+
+	remote := NewRemoteKeySet(server.URL, server.Client(), 0)
+	defer remote.Close()
+	err := remote.Refresh(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "larger than")
+}
+
+func TestRemoteKeySet_Concurrent(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	now := time.Now()
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	ks := testKeySet()
+	jwks := &jwksServer{}
+	jwks.rotate(ks, `"v1"`)
+	server := httptest.NewServer(jwks)
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	remote := NewRemoteKeySet(server.URL, client, 0)
+	defer remote.Close()
+
+	// Одновременные запросы неизвестных ключей приводят к одной загрузке
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := remote.Key("missing" + strconv.Itoa(i))
+			require.ErrorIs(t, err, ErrKeyNotFound)
+		}(i)
+	}
+	wg.Wait()
+	require.Equal(t, 1, jwks.count())
+}
+
+func TestRemoteKeySet_Timeout(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	timeFunc = time.Now
+	defer func(timeout time.Duration) { remoteFetchTimeout = timeout }(remoteFetchTimeout)
+	remoteFetchTimeout = 50 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	remote := NewRemoteKeySet(server.URL, client, 0)
+	defer remote.Close()
+	_, err := remote.Key("hmac")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,80 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrTokenTooLarge = errors.New("token too large")
+
+const (
+	defaultMaxTokenSize  = 16 << 10
+	defaultMaxHeaderSize = 1 << 10
+	defaultMaxDepth      = 32
+)
+
+// Limits ограничивает ресурсы, которые тратятся на разбор недоверенного токена.
+// Нулевые значения заменяются значениями по умолчанию
+type Limits struct {
+	// TokenSize - максимальный размер токена в байтах
+	TokenSize int
+	// HeaderSize - максимальный размер закодированного заголовка в байтах
+	HeaderSize int
+	// Depth - максимальная вложенность json в заголовке и содержимом
+	Depth int
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.TokenSize <= 0 {
+		l.TokenSize = defaultMaxTokenSize
+	}
+	if l.HeaderSize <= 0 {
+		l.HeaderSize = defaultMaxHeaderSize
+	}
+	if l.Depth <= 0 {
+		l.Depth = defaultMaxDepth
+	}
+	return l
+}
+
+func (l Limits) checkToken(token []byte) error {
+	if len(token) > l.TokenSize {
+		return fmt.Errorf("%w: %d bytes, limit is %d", ErrTokenTooLarge, len(token), l.TokenSize)
+	}
+	return nil
+}
+
+func (l Limits) checkHeader(segment []byte) error {
+	if len(segment) > l.HeaderSize {
+		return fmt.Errorf("%w: header is %d bytes, limit is %d", ErrTokenTooLarge, len(segment), l.HeaderSize)
+	}
+	return nil
+}
+
+// checkDepth проверяет, что вложенность объектов и массивов в json не превышает лимит.
+// Проверка выполняется до json.Unmarshal, чтобы не тратить на глубокие документы память и стек
+func (l Limits) checkDepth(raw []byte) error {
+	depth := 0
+	inString, escaped := false, false
+	for _, c := range raw {
+		switch {
+		case inString && escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case inString && c == '"':
+			inString = false
+		case inString:
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+			if depth > l.Depth {
+				return fmt.Errorf("%w: json nesting is deeper than %d", ErrInvalidToken, l.Depth)
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return nil
+}
@@ -1,6 +1,10 @@
 package jwt
 
-import "time"
+import (
+	"crypto"
+	"fmt"
+	"time"
+)
 
 type Option func(*config)
 
@@ -16,6 +20,39 @@ func WithKey(k []byte) Option {
 	}
 }
 
+// WithPrivateKey задает ключ для подписи асимметричными методами
+// (*rsa.PrivateKey, *ecdsa.PrivateKey или ed25519.PrivateKey).
+// При проверке подписи используется соответствующий ему открытый ключ
+func WithPrivateKey(k crypto.Signer) Option {
+	return func(c *config) {
+		c.PrivateKey = k
+	}
+}
+
+// WithPublicKey задает ключ для проверки подписи асимметричными методами
+// (*rsa.PublicKey, *ecdsa.PublicKey или ed25519.PublicKey)
+func WithPublicKey(k crypto.PublicKey) Option {
+	return func(c *config) {
+		c.PublicKey = k
+	}
+}
+
+// WithKeyID задает идентификатор ключа, который записывается в заголовок `kid` токена.
+// Вместе с WithKeySet ключ для подписи выбирается из набора
+func WithKeyID(kid string) Option {
+	return func(c *config) {
+		c.KeyID = kid
+	}
+}
+
+// WithKeySet задает набор ключей. При проверке ключ выбирается по заголовку `kid`
+// токена, при подписи - по идентификатору из WithKeyID
+func WithKeySet(ks KeyProvider) Option {
+	return func(c *config) {
+		c.KeySet = ks
+	}
+}
+
 func WithTTL(ttl time.Duration) Option {
 	return func(c *config) {
 		c.TTL = &ttl
@@ -28,9 +65,283 @@ func WithExpires(t time.Time) Option {
 	}
 }
 
+// WithIssuer задает издателя токена (`iss`). При проверке издатель токена должен совпадать
+func WithIssuer(iss string) Option {
+	return func(c *config) {
+		c.Issuer = iss
+	}
+}
+
+// WithSubject задает субъекта токена (`sub`). При проверке субъект токена должен совпадать
+func WithSubject(sub string) Option {
+	return func(c *config) {
+		c.Subject = sub
+	}
+}
+
+// WithAudience задает получателей токена (`aud`). При проверке среди получателей
+// токена должен быть хотя бы один из переданных
+func WithAudience(aud ...string) Option {
+	return func(c *config) {
+		c.Audience = aud
+	}
+}
+
+// WithNotBefore задает время, раньше которого токен недействителен (`nbf`)
+func WithNotBefore(t time.Time) Option {
+	return func(c *config) {
+		c.NotBefore = &t
+	}
+}
+
+// WithIssuedAt добавляет в токен время его создания (`iat`)
+func WithIssuedAt() Option {
+	return func(c *config) {
+		c.IssuedAt = true
+	}
+}
+
+// WithID задает идентификатор токена (`jti`). При проверке идентификатор токена должен совпадать
+func WithID(jti string) Option {
+	return func(c *config) {
+		c.ID = jti
+	}
+}
+
+// WithLeeway задает допустимое расхождение часов при проверке `exp`, `nbf` и `iat`
+func WithLeeway(d time.Duration) Option {
+	return func(c *config) {
+		c.Leeway = d
+	}
+}
+
+// WithClaims задает структуру, которую Decode заполняет зарегистрированными полями токена
+func WithClaims(claims *Claims) Option {
+	return func(c *config) {
+		c.Claims = claims
+	}
+}
+
+// WithRevoker задает хранилище отозванных токенов, по которому Decode проверяет `jti`
+func WithRevoker(r Revoker) Option {
+	return func(c *config) {
+		c.Revoker = r
+	}
+}
+
+// WithKeyAlgorithm задает способ получения ключа шифрования для JWE
+func WithKeyAlgorithm(alg KeyAlgorithm) Option {
+	return func(c *config) {
+		c.KeyAlgorithm = alg
+	}
+}
+
+// WithEncryptionKey задает ключ для JWE: ключ шифрования содержимого для Dir
+// или ключ для шифрования ключа содержимого для A128KW и A256KW
+func WithEncryptionKey(k []byte) Option {
+	return func(c *config) {
+		c.EncryptionKey = k
+	}
+}
+
+// NumberMode - способ декодирования чисел в пользовательских данных типа interface{}
+type NumberMode int
+
+const (
+	// NumberFloat64 декодирует числа в float64
+	NumberFloat64 NumberMode = iota
+	// NumberJSON декодирует числа в json.Number без потери точности
+	NumberJSON
+)
+
+// WithNumberMode задает способ декодирования чисел при проверке токена
+func WithNumberMode(m NumberMode) Option {
+	return func(c *config) {
+		c.NumberMode = m
+	}
+}
+
+// WithHeader задает структуру, которую Decode заполняет заголовком токена
+func WithHeader(h *Header) Option {
+	return func(c *config) {
+		c.Header = h
+	}
+}
+
+// KeyFunc выбирает ключ для проверки по непроверенным заголовку и содержимому токена.
+// Метод подписи из заголовка должен совпадать с методом ключа или заданным через WithSignMethod,
+// а тип ключа - подходить для метода
+type KeyFunc func(h Header, claims RawClaims) (JWK, error)
+
+// WithKeyFunc задает функцию выбора ключа для Decode
+func WithKeyFunc(f KeyFunc) Option {
+	return func(c *config) {
+		c.KeyFunc = f
+	}
+}
+
+// WithFlatClaims записывает поля пользовательских данных (структуры или map) рядом
+// с зарегистрированными полями вместо вложения в поле `d`. Decode в этом режиме
+// заполняет данные всеми полями, кроме зарегистрированных
+func WithFlatClaims() Option {
+	return func(c *config) {
+		c.FlatClaims = true
+	}
+}
+
+// WithLimits задает ограничения на размер и вложенность проверяемого токена
+func WithLimits(l Limits) Option {
+	return func(c *config) {
+		c.Limits = l
+	}
+}
+
+// WithFlattenedJSON включает для EncodeJSON упрощенную форму json-сериализации с одной подписью
+func WithFlattenedJSON() Option {
+	return func(c *config) {
+		c.FlattenedJSON = true
+	}
+}
+
+// WithSignaturePolicy задает, сколько подписей должно быть верными при DecodeJSON.
+// По умолчанию требуются все подписи
+func WithSignaturePolicy(p SignaturePolicy) Option {
+	return func(c *config) {
+		c.SignaturePolicy = p
+	}
+}
+
+// WithRequiredKeys задает ключи, подпись каждым из которых обязательна при DecodeJSON
+// независимо от WithSignaturePolicy. Без нее подпись можно удалить из токена незаметно
+// для RequireAll, так как проверяются только имеющиеся подписи
+func WithRequiredKeys(kids ...string) Option {
+	return func(c *config) {
+		c.RequiredKeys = append([]string{}, kids...)
+	}
+}
+
+// WithJWK задает ключ для подписи или проверки. Метод подписи и идентификатор ключа
+// берутся из `k`, если не заданы явно
+func WithJWK(k JWK) Option {
+	return func(c *config) {
+		switch key := k.Key.(type) {
+		case []byte:
+			c.Key = key
+		case crypto.Signer:
+			c.PrivateKey = key
+		default:
+			c.PublicKey = key
+		}
+		if c.SignMethod == "" {
+			c.SignMethod = k.Algorithm
+		}
+		if c.KeyID == "" {
+			c.KeyID = k.KeyID
+		}
+	}
+}
+
 type config struct {
 	SignMethod SignMethod
 	Key        []byte
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+	KeyID      string
+	KeySet     KeyProvider
+	KeyFunc    KeyFunc
 	TTL        *time.Duration
 	Expires    *time.Time
+	Issuer     string
+	Subject    string
+	Audience   []string
+	NotBefore  *time.Time
+	IssuedAt   bool
+	ID         string
+	Leeway     time.Duration
+	Claims     *Claims
+	Header     *Header
+	NumberMode NumberMode
+	FlatClaims bool
+	Limits     Limits
+	Revoker    Revoker
+
+	KeyAlgorithm  KeyAlgorithm
+	EncryptionKey []byte
+
+	FlattenedJSON   bool
+	SignaturePolicy SignaturePolicy
+	RequiredKeys    []string
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.Limits = c.Limits.withDefaults()
+	return c
+}
+
+// expiration возвращает время истечения токена в секундах или nil,
+// если время жизни токена не ограничено
+func (c *config) expiration() (*int64, error) {
+	var exp time.Time
+	switch {
+	case c.TTL != nil && c.Expires != nil:
+		return nil, ErrConfigurationMalformed
+	case c.TTL != nil:
+		exp = timeFunc().Add(*c.TTL)
+	case c.Expires != nil:
+		if c.Expires.Before(timeFunc()) {
+			return nil, ErrConfigurationMalformed
+		}
+		exp = *c.Expires
+	default:
+		return nil, nil
+	}
+	unix := exp.Unix()
+	return &unix, nil
+}
+
+// publicKey возвращает ключ для проверки подписи
+func (c *config) publicKey() crypto.PublicKey {
+	if c.PublicKey == nil && c.PrivateKey != nil {
+		return c.PrivateKey.Public()
+	}
+	return c.PublicKey
+}
+
+// withJWK возвращает копию конфигурации с ключом `k` из набора ключей. Метод подписи
+// берется из ключа, если не задан явно, и должен совпадать с `alg`
+func (c *config) withJWK(k JWK, alg SignMethod) (*config, error) {
+	res := *c
+	if k.Algorithm != "" {
+		if alg != k.Algorithm {
+			return nil, fmt.Errorf("%w: key %q is for %s", ErrSignMethodMismatched, k.KeyID, k.Algorithm)
+		}
+		if res.SignMethod == "" {
+			res.SignMethod = k.Algorithm
+		}
+	}
+	switch key := k.Key.(type) {
+	case []byte:
+		res.Key = key
+	case crypto.Signer:
+		res.PrivateKey = key
+	default:
+		res.PublicKey = key
+	}
+	return &res, nil
+}
+
+// withoutKeys возвращает копию конфигурации без явно заданных ключей
+func (c *config) withoutKeys() *config {
+	res := *c
+	res.Key, res.PrivateKey, res.PublicKey = nil, nil, nil
+	return &res
+}
+
+// hasKey проверяет, что ключ задан явно, а не через набор ключей
+func (c *config) hasKey() bool {
+	return len(c.Key) != 0 || c.PrivateKey != nil || c.PublicKey != nil
 }
@@ -0,0 +1,112 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Имена зарегистрированных полей, которые в режиме WithFlatClaims не попадают в пользовательские данные
+var registeredClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+// marshalPayload кодирует содержимое токена. По умолчанию данные вкладываются в поле `d`,
+// в режиме WithFlatClaims поля данных записываются рядом с зарегистрированными
+func (c *config) marshalPayload(data interface{}, claims Claims) ([]byte, error) {
+	if !c.FlatClaims {
+		return json.Marshal(payload{Data: data, Claims: claims})
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("%w: flat claims require an object, got %T", ErrConfigurationMalformed, data)
+	}
+	if raw, err = json.Marshal(claims); err != nil {
+		return nil, err
+	}
+	var registered map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &registered); err != nil {
+		return nil, err
+	}
+	for name, value := range registered {
+		if _, ok := fields[name]; ok {
+			return nil, fmt.Errorf("%w: claim %q is set twice", ErrConfigurationMalformed, name)
+		}
+		fields[name] = value
+	}
+	return json.Marshal(fields)
+}
+
+// unmarshalPayload разбирает содержимое токена, в режиме WithFlatClaims
+// отделяя пользовательские данные от зарегистрированных полей
+func (c *config) unmarshalPayload(raw []byte) (RawClaims, error) {
+	var p RawClaims
+	if err := c.Limits.checkDepth(raw); err != nil {
+		return p, err
+	}
+	dates := struct {
+		*RawClaims
+		ExpiresAt *numericDate `json:"exp,omitempty"`
+		NotBefore *numericDate `json:"nbf,omitempty"`
+		IssuedAt  *numericDate `json:"iat,omitempty"`
+	}{RawClaims: &p}
+	if err := json.Unmarshal(raw, &dates); err != nil {
+		return p, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	p.ExpiresAt = dates.ExpiresAt.seconds()
+	p.NotBefore = dates.NotBefore.seconds()
+	p.IssuedAt = dates.IssuedAt.seconds()
+	if !c.FlatClaims {
+		return p, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return p, fmt.Errorf("%w: payload is not an object", ErrInvalidToken)
+	}
+	for _, name := range registeredClaims {
+		delete(fields, name)
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return p, err
+	}
+	p.Data = data
+	return p, nil
+}
+
+// numericDate - время в секундах (RFC 7519, раздел 2). Значение может быть дробным,
+// дробная часть отбрасывается
+type numericDate int64
+
+func (d *numericDate) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if len(data) > 0 && data[0] == '"' {
+		return fmt.Errorf("numeric date %s is not a number", data)
+	}
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	if v, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		*d = numericDate(v)
+		return nil
+	}
+	v, err := strconv.ParseFloat(string(n), 64)
+	if err != nil || v < math.MinInt64 || v >= math.MaxInt64 {
+		return fmt.Errorf("numeric date %s is out of range", n)
+	}
+	*d = numericDate(math.Trunc(v))
+	return nil
+}
+
+func (d *numericDate) seconds() *int64 {
+	if d == nil {
+		return nil
+	}
+	v := int64(*d)
+	return &v
+}
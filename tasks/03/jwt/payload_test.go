@@ -0,0 +1,106 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type person struct {
+	Name  string `json:"name"`
+	Admin bool   `json:"admin,omitempty"`
+}
+
+func TestFlatClaims_Interop(t *testing.T) {
+	timeFunc = time.Now
+	// Токен с jwt.io
+	token := []byte("eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9." +
+		"eyJzdWIiOiIxMjM0NTY3ODkwIiwibmFtZSI6IkpvaG4gRG9lIiwiaWF0IjoxNTE2MjM5MDIyfQ." +
+		"SflKxwRJSMeKKF2QT4fwpMeJf36POk6yJV_adQssw5c")
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("your-256-bit-secret"))}
+
+	var p person
+	var claims Claims
+	require.NoError(t, Decode(token, &p, append(opts, WithFlatClaims(), WithClaims(&claims))...))
+	require.Equal(t, person{Name: "John Doe"}, p)
+	require.Equal(t, "1234567890", claims.Subject)
+	require.Equal(t, int64(1516239022), *claims.IssuedAt)
+
+	var m map[string]interface{}
+	require.NoError(t, Decode(token, &m, append(opts, WithFlatClaims())...))
+	require.Equal(t, map[string]interface{}{"name": "John Doe"}, m)
+
+	// Без флага данные ищутся в поле `d`
+	require.ErrorIs(t, Decode(token, &m, opts...), ErrInvalidToken)
+}
+
+func TestFlatClaims_Encode(t *testing.T) {
+	now := time.Unix(10, 0)
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret")), WithFlatClaims()}
+
+	token, err := Encode(person{Name: "Ivan", Admin: true}, append(opts, WithSubject("42"), WithTTL(90*time.Second))...)
+	require.NoError(t, err)
+	_, raw, err := ParseUnverified(token)
+	require.NoError(t, err)
+	require.Equal(t, "42", raw.Subject)
+	require.Equal(t, int64(100), *raw.ExpiresAt)
+
+	var p person
+	require.NoError(t, Decode(token, &p, opts...))
+	require.Equal(t, person{Name: "Ivan", Admin: true}, p)
+
+	_, err = Encode(map[string]string{"sub": "1"}, append(opts, WithSubject("2"))...)
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	_, err = Encode("scalar", opts...)
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+
+	// Зарегистрированное поле из данных проверяется как обычно
+	token, err = Encode(map[string]interface{}{"iss": "other", "name": "Ivan"}, opts...)
+	require.NoError(t, err)
+	require.ErrorIs(t, Decode(token, &p, append(opts, WithIssuer("auth"))...), ErrInvalidIssuer)
+}
+
+func TestFlatClaims_Encrypted(t *testing.T) {
+	timeFunc = time.Now
+	opts := []Option{WithKeyAlgorithm(Dir), WithEncryptionKey(make([]byte, 32)), WithFlatClaims()}
+	token, err := EncodeEncrypted(person{Name: "Ivan"}, append(opts, WithIssuer("auth"))...)
+	require.NoError(t, err)
+
+	var p person
+	var claims Claims
+	require.NoError(t, DecodeEncrypted(token, &p, append(opts, WithClaims(&claims))...))
+	require.Equal(t, person{Name: "Ivan"}, p)
+	require.Equal(t, "auth", claims.Issuer)
+}
+
+func TestNumericDate_Fractional(t *testing.T) {
+	now := time.Unix(1000, 0)
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret")), WithFlatClaims()}
+
+	token, err := Encode(map[string]interface{}{"exp": 1001.9, "iat": 999.5}, opts...)
+	require.NoError(t, err)
+	var m map[string]interface{}
+	var claims Claims
+	require.NoError(t, Decode(token, &m, append(opts, WithClaims(&claims))...))
+	require.Equal(t, int64(1001), *claims.ExpiresAt)
+	require.Equal(t, int64(999), *claims.IssuedAt)
+
+	// Дробная часть отбрасывается, а не округляется
+	token, err = Encode(map[string]interface{}{"exp": 1000.5}, opts...)
+	require.NoError(t, err)
+	require.ErrorIs(t, Decode(token, &m, opts...), ErrTokenExpired)
+
+	for _, payload := range []string{`{"exp":"1000"}`, `{"nbf":1e30}`, `{"iat":true}`} {
+		_, err := newConfig(nil).unmarshalPayload([]byte(payload))
+		require.ErrorIs(t, err, ErrInvalidToken, payload)
+	}
+	p, err := newConfig(nil).unmarshalPayload([]byte(`{"d":1,"exp":1.7e9,"nbf":null}`))
+	require.NoError(t, err)
+	require.Equal(t, int64(1700000000), *p.ExpiresAt)
+	require.Nil(t, p.NotBefore)
+}
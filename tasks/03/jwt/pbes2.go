@@ -0,0 +1,148 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+// Зашифрованные ключи PKCS#8 (RFC 5208, раздел 6) со схемой PBES2 (RFC 8018, раздел 6.2):
+// ключ шифрования получается из пароля через PBKDF2, ключ шифруется AES-CBC
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// maxPBKDF2Iterations ограничивает работу на разбор одного ключа: файл с огромным
+// числом итераций иначе занимает процессор на минуты
+const maxPBKDF2Iterations = 10_000_000
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Data      []byte
+}
+
+type pbes2Params struct {
+	KeyDerivation pkix.AlgorithmIdentifier
+	Encryption    pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decryptPKCS8 расшифровывает EncryptedPrivateKeyInfo и возвращает PKCS#8 PrivateKeyInfo
+func decryptPKCS8(der, password []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("%w: unsupported encryption %v", ErrInvalidKey, info.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	keySize, ok := map[string]int{oidAES128CBC.String(): 16, oidAES256CBC.String(): 32}[params.Encryption.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported cipher %v", ErrInvalidKey, params.Encryption.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.Encryption.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("%w: invalid iv", ErrInvalidKey)
+	}
+	key, err := pbes2Key(params.KeyDerivation, password, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(info.Data) == 0 || len(info.Data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: invalid encrypted data size", ErrInvalidKey)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]byte, len(info.Data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(res, info.Data)
+	return trimPadding(res, aes.BlockSize)
+}
+
+func pbes2Key(kdf pkix.AlgorithmIdentifier, password []byte, keySize int) ([]byte, error) {
+	if !kdf.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("%w: unsupported key derivation %v", ErrInvalidKey, kdf.Algorithm)
+	}
+	var params pbkdf2Params
+	if _, err := asn1.Unmarshal(kdf.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	if params.Iterations <= 0 || params.Iterations > maxPBKDF2Iterations || (params.KeyLength != 0 && params.KeyLength != keySize) {
+		return nil, fmt.Errorf("%w: invalid pbkdf2 parameters", ErrInvalidKey)
+	}
+	var h func() hash.Hash
+	switch {
+	case len(params.PRF.Algorithm) == 0, params.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		h = sha1.New
+	case params.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		h = sha256.New
+	default:
+		return nil, fmt.Errorf("%w: unsupported prf %v", ErrInvalidKey, params.PRF.Algorithm)
+	}
+	return pbkdf2(h, password, params.Salt, params.Iterations, keySize), nil
+}
+
+// pbkdf2 реализует PBKDF2 (RFC 8018, раздел 5.2)
+func pbkdf2(h func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	size := prf.Size()
+	res := make([]byte, 0, (keyLen+size-1)/size*size)
+	var counter [4]byte
+	u := make([]byte, 0, size)
+	for block := uint32(1); len(res) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], block)
+		prf.Write(counter[:])
+		res = prf.Sum(res)
+		t := res[len(res)-size:]
+		u = append(u[:0], t...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range u {
+				t[j] ^= u[j]
+			}
+		}
+	}
+	return res[:keyLen]
+}
+
+// trimPadding снимает дополнение PKCS#7
+func trimPadding(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, ErrKeyPassword
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || !bytes.Equal(data[len(data)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, ErrKeyPassword
+	}
+	return data[:len(data)-n], nil
+}
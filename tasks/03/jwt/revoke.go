@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrTokenRevoked = errors.New("token revoked")
+
+// Revoker хранит идентификаторы (`jti`) отозванных токенов. Запись можно удалить
+// после `expires`: к этому моменту токен и так станет недействительным.
+// Нулевое `expires` означает, что токен бессрочный
+type Revoker interface {
+	Revoke(jti string, expires time.Time) error
+	IsRevoked(jti string) (bool, error)
+}
+
+// DefaultRevocationGrace - сколько по умолчанию хранится запись после истечения токена
+const DefaultRevocationGrace = 5 * time.Minute
+
+// RevokerConfig - параметры хранилищ отозванных токенов
+type RevokerConfig struct {
+	// Grace - сколько запись хранится после истечения токена. Decode принимает токен
+	// до `exp` + WithLeeway, поэтому Grace должно быть не меньше любого используемого
+	// расхождения часов, иначе отозванный токен снова пройдет проверку
+	Grace time.Duration
+}
+
+type RevokerOption func(*RevokerConfig)
+
+// WithRevocationGrace задает, сколько запись хранится после истечения токена
+func WithRevocationGrace(d time.Duration) RevokerOption {
+	return func(c *RevokerConfig) {
+		c.Grace = d
+	}
+}
+
+// NewRevokerConfig возвращает параметры по умолчанию с примененными `opts`
+func NewRevokerConfig(opts ...RevokerOption) RevokerConfig {
+	c := RevokerConfig{Grace: DefaultRevocationGrace}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// RevokeClaims отзывает токен с полями `claims`
+func RevokeClaims(r Revoker, claims Claims) error {
+	if claims.ID == "" {
+		return ErrInvalidID
+	}
+	var expires time.Time
+	if claims.ExpiresAt != nil {
+		expires = time.Unix(*claims.ExpiresAt, 0)
+	}
+	return r.Revoke(claims.ID, expires)
+}
+
+// MemoryRevoker хранит отозванные токены в памяти и удаляет записи через RevokerConfig.Grace
+// после истечения токенов
+type MemoryRevoker struct {
+	mutex   sync.Mutex
+	grace   time.Duration
+	expires map[string]time.Time
+	queue   revokedQueue
+}
+
+func NewMemoryRevoker(opts ...RevokerOption) *MemoryRevoker {
+	return &MemoryRevoker{grace: NewRevokerConfig(opts...).Grace, expires: make(map[string]time.Time)}
+}
+
+func (r *MemoryRevoker) Revoke(jti string, expires time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.evict()
+	if _, ok := r.expires[jti]; ok {
+		return nil
+	}
+	r.expires[jti] = expires
+	if !expires.IsZero() {
+		heap.Push(&r.queue, revoked{jti: jti, expires: expires})
+	}
+	return nil
+}
+
+func (r *MemoryRevoker) IsRevoked(jti string) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.evict()
+	_, ok := r.expires[jti]
+	return ok, nil
+}
+
+// Len возвращает количество хранимых записей
+func (r *MemoryRevoker) Len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.evict()
+	return len(r.expires)
+}
+
+// evict удаляет записи о токенах, истекших больше grace назад
+func (r *MemoryRevoker) evict() {
+	now := timeFunc()
+	for len(r.queue) > 0 && !now.Before(r.queue[0].expires.Add(r.grace)) {
+		delete(r.expires, heap.Pop(&r.queue).(revoked).jti)
+	}
+}
+
+type revoked struct {
+	jti     string
+	expires time.Time
+}
+
+// revokedQueue - куча записей, упорядоченная по времени истечения
+type revokedQueue []revoked
+
+func (q revokedQueue) Len() int           { return len(q) }
+func (q revokedQueue) Less(i, j int) bool { return q[i].expires.Before(q[j].expires) }
+func (q revokedQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *revokedQueue) Push(x interface{}) {
+	*q = append(*q, x.(revoked))
+}
+
+func (q *revokedQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
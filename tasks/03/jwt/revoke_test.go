@@ -0,0 +1,81 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRevoker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	r := NewMemoryRevoker()
+	require.NoError(t, r.Revoke("a", now.Add(2*time.Minute)))
+	require.NoError(t, r.Revoke("b", now.Add(time.Minute)))
+	require.NoError(t, r.Revoke("c", time.Time{}))
+	require.Equal(t, 3, r.Len())
+
+	// Записи хранятся еще DefaultRevocationGrace после истечения токенов
+	now = now.Add(time.Minute + DefaultRevocationGrace)
+	revoked, err := r.IsRevoked("b")
+	require.NoError(t, err)
+	require.False(t, revoked)
+	revoked, err = r.IsRevoked("a")
+	require.NoError(t, err)
+	require.True(t, revoked)
+	require.Equal(t, 2, r.Len())
+
+	now = now.Add(time.Hour)
+	require.Equal(t, 1, r.Len())
+	revoked, err = r.IsRevoked("c")
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestDecode_RevokedWithinLeeway(t *testing.T) {
+	now := time.Unix(1000, 0)
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	r := NewMemoryRevoker(WithRevocationGrace(2 * time.Minute))
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret")), WithRevoker(r), WithLeeway(time.Minute)}
+	token, err := Encode("hello", append(opts, WithID("1"), WithTTL(time.Minute))...)
+	require.NoError(t, err)
+	var data string
+	var claims Claims
+	require.NoError(t, Decode(token, &data, append(opts, WithClaims(&claims))...))
+	require.NoError(t, RevokeClaims(r, claims))
+
+	// Токен истек, но еще принимается с учетом расхождения часов: отзыв должен действовать
+	now = now.Add(90 * time.Second)
+	require.NoError(t, Decode(token, &data, WithSignMethod(HS256), WithKey([]byte("secret")), WithLeeway(time.Minute)))
+	require.ErrorIs(t, Decode(token, &data, opts...), ErrTokenRevoked)
+
+	now = now.Add(2 * time.Minute)
+	require.ErrorIs(t, Decode(token, &data, opts...), ErrTokenExpired)
+	require.Equal(t, 0, r.Len())
+}
+
+func TestDecode_Revoked(t *testing.T) {
+	timeFunc = time.Now
+	r := NewMemoryRevoker()
+	opts := []Option{WithSignMethod(HS256), WithKey([]byte("secret")), WithRevoker(r)}
+
+	token, err := Encode("hello", append(opts, WithID("1"), WithTTL(time.Hour))...)
+	require.NoError(t, err)
+	// Токены без jti отозвать нельзя
+	plain, err := Encode("hello", opts...)
+	require.NoError(t, err)
+
+	var data string
+	var claims Claims
+	require.NoError(t, Decode(token, &data, append(opts, WithClaims(&claims))...))
+	require.NoError(t, RevokeClaims(r, claims))
+	require.ErrorIs(t, Decode(token, &data, opts...), ErrTokenRevoked)
+	require.NoError(t, Decode(token, &data, WithSignMethod(HS256), WithKey([]byte("secret"))))
+	require.NoError(t, Decode(plain, &data, opts...))
+	require.ErrorIs(t, RevokeClaims(r, Claims{}), ErrInvalidID)
+}
@@ -0,0 +1,254 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"fmt"
+	"math/big"
+)
+
+// signer подписывает токены и проверяет их подпись
+type signer interface {
+	Sign(data []byte) ([]byte, error)
+	Verify(data, signature []byte) error
+}
+
+var signMethods = map[SignMethod]func(*config) (signer, error){
+	HS256: newHMACSigner(crypto.SHA256),
+	HS384: newHMACSigner(crypto.SHA384),
+	HS512: newHMACSigner(crypto.SHA512),
+	RS256: newRSASigner(crypto.SHA256, false),
+	RS384: newRSASigner(crypto.SHA384, false),
+	RS512: newRSASigner(crypto.SHA512, false),
+	PS256: newRSASigner(crypto.SHA256, true),
+	ES256: newECDSASigner(crypto.SHA256, elliptic.P256()),
+	ES384: newECDSASigner(crypto.SHA384, elliptic.P384()),
+	EdDSA: newEd25519Signer,
+}
+
+func newSigner(cfg *config) (signer, error) {
+	newMethod, ok := signMethods[cfg.SignMethod]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSignMethod, cfg.SignMethod)
+	}
+	return newMethod(cfg)
+}
+
+func keyMismatched(m SignMethod, key interface{}) error {
+	return fmt.Errorf("%w: key of type %T can not be used with %s", ErrConfigurationMalformed, key, m)
+}
+
+func keyMissing(kind string) error {
+	return fmt.Errorf("%w: %s key is not set", ErrConfigurationMalformed, kind)
+}
+
+func digest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	_, _ = h.Write(data)
+	return h.Sum(nil)
+}
+
+type hmacSigner struct {
+	hash crypto.Hash
+	key  []byte
+}
+
+func newHMACSigner(hash crypto.Hash) func(*config) (signer, error) {
+	return func(cfg *config) (signer, error) {
+		if public := cfg.publicKey(); public != nil {
+			return nil, keyMismatched(cfg.SignMethod, public)
+		}
+		if len(cfg.Key) == 0 {
+			return nil, keyMissing("hmac")
+		}
+		return &hmacSigner{hash: hash, key: cfg.Key}, nil
+	}
+}
+
+func (s *hmacSigner) Sign(data []byte) ([]byte, error) {
+	h := hmac.New(s.hash.New, s.key)
+	_, _ = h.Write(data)
+	return h.Sum(nil), nil
+}
+
+func (s *hmacSigner) Verify(data, signature []byte) error {
+	expected, _ := s.Sign(data)
+	if !hmac.Equal(expected, signature) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+type rsaSigner struct {
+	hash    crypto.Hash
+	pss     bool
+	private *rsa.PrivateKey
+	public  *rsa.PublicKey
+}
+
+func newRSASigner(hash crypto.Hash, pss bool) func(*config) (signer, error) {
+	return func(cfg *config) (signer, error) {
+		s := &rsaSigner{hash: hash, pss: pss}
+		if len(cfg.Key) != 0 {
+			return nil, keyMismatched(cfg.SignMethod, cfg.Key)
+		}
+		var ok bool
+		if cfg.PrivateKey != nil {
+			if s.private, ok = cfg.PrivateKey.(*rsa.PrivateKey); !ok {
+				return nil, keyMismatched(cfg.SignMethod, cfg.PrivateKey)
+			}
+		}
+		if public := cfg.publicKey(); public != nil {
+			if s.public, ok = public.(*rsa.PublicKey); !ok {
+				return nil, keyMismatched(cfg.SignMethod, public)
+			}
+		}
+		return s, nil
+	}
+}
+
+func (s *rsaSigner) pssOptions() *rsa.PSSOptions {
+	return &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: s.hash}
+}
+
+func (s *rsaSigner) Sign(data []byte) ([]byte, error) {
+	if s.private == nil {
+		return nil, keyMissing("rsa private")
+	}
+	if s.pss {
+		return rsa.SignPSS(rand.Reader, s.private, s.hash, digest(s.hash, data), s.pssOptions())
+	}
+	return rsa.SignPKCS1v15(rand.Reader, s.private, s.hash, digest(s.hash, data))
+}
+
+func (s *rsaSigner) Verify(data, signature []byte) error {
+	if s.public == nil {
+		return keyMissing("rsa public")
+	}
+	var err error
+	if s.pss {
+		err = rsa.VerifyPSS(s.public, s.hash, digest(s.hash, data), signature, s.pssOptions())
+	} else {
+		err = rsa.VerifyPKCS1v15(s.public, s.hash, digest(s.hash, data), signature)
+	}
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+type ecdsaSigner struct {
+	hash    crypto.Hash
+	curve   elliptic.Curve
+	private *ecdsa.PrivateKey
+	public  *ecdsa.PublicKey
+}
+
+func newECDSASigner(hash crypto.Hash, curve elliptic.Curve) func(*config) (signer, error) {
+	return func(cfg *config) (signer, error) {
+		s := &ecdsaSigner{hash: hash, curve: curve}
+		if len(cfg.Key) != 0 {
+			return nil, keyMismatched(cfg.SignMethod, cfg.Key)
+		}
+		var ok bool
+		if cfg.PrivateKey != nil {
+			s.private, ok = cfg.PrivateKey.(*ecdsa.PrivateKey)
+			if !ok || s.private.Curve != curve {
+				return nil, keyMismatched(cfg.SignMethod, cfg.PrivateKey)
+			}
+		}
+		if public := cfg.publicKey(); public != nil {
+			s.public, ok = public.(*ecdsa.PublicKey)
+			if !ok || s.public.Curve != curve {
+				return nil, keyMismatched(cfg.SignMethod, public)
+			}
+		}
+		return s, nil
+	}
+}
+
+// keySize - размер каждой из половин подписи (r и s) в байтах
+func (s *ecdsaSigner) keySize() int {
+	return (s.curve.Params().BitSize + 7) / 8
+}
+
+func (s *ecdsaSigner) Sign(data []byte) ([]byte, error) {
+	if s.private == nil {
+		return nil, keyMissing("ecdsa private")
+	}
+	r, sig_s, err := ecdsa.Sign(rand.Reader, s.private, digest(s.hash, data))
+	if err != nil {
+		return nil, err
+	}
+	size := s.keySize()
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	sig_s.FillBytes(signature[size:])
+	return signature, nil
+}
+
+func (s *ecdsaSigner) Verify(data, signature []byte) error {
+	if s.public == nil {
+		return keyMissing("ecdsa public")
+	}
+	size := s.keySize()
+	if len(signature) != 2*size {
+		return ErrSignatureInvalid
+	}
+	r := new(big.Int).SetBytes(signature[:size])
+	sig_s := new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(s.public, digest(s.hash, data), r, sig_s) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+type ed25519Signer struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+func newEd25519Signer(cfg *config) (signer, error) {
+	s := &ed25519Signer{}
+	if len(cfg.Key) != 0 {
+		return nil, keyMismatched(cfg.SignMethod, cfg.Key)
+	}
+	var ok bool
+	if cfg.PrivateKey != nil {
+		s.private, ok = cfg.PrivateKey.(ed25519.PrivateKey)
+		if !ok || len(s.private) != ed25519.PrivateKeySize {
+			return nil, keyMismatched(cfg.SignMethod, cfg.PrivateKey)
+		}
+	}
+	if public := cfg.publicKey(); public != nil {
+		s.public, ok = public.(ed25519.PublicKey)
+		if !ok || len(s.public) != ed25519.PublicKeySize {
+			return nil, keyMismatched(cfg.SignMethod, public)
+		}
+	}
+	return s, nil
+}
+
+func (s *ed25519Signer) Sign(data []byte) ([]byte, error) {
+	if s.private == nil {
+		return nil, keyMissing("ed25519 private")
+	}
+	return ed25519.Sign(s.private, data), nil
+}
+
+func (s *ed25519Signer) Verify(data, signature []byte) error {
+	if s.public == nil {
+		return keyMissing("ed25519 public")
+	}
+	if !ed25519.Verify(s.public, data, signature) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testRSAKey     = mustRSAKey()
+	testP256Key    = mustECDSAKey(elliptic.P256())
+	testP384Key    = mustECDSAKey(elliptic.P384())
+	testEd25519Key = mustEd25519Key()
+)
+
+func mustRSAKey() *rsa.PrivateKey {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func mustECDSAKey(curve elliptic.Curve) *ecdsa.PrivateKey {
+	k, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func mustEd25519Key() ed25519.PrivateKey {
+	_, k, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+var asymmetricTestCases = []struct {
+	Method SignMethod
+	Key    crypto.Signer
+}{
+	{Method: RS256, Key: testRSAKey},
+	{Method: RS384, Key: testRSAKey},
+	{Method: RS512, Key: testRSAKey},
+	{Method: PS256, Key: testRSAKey},
+	{Method: ES256, Key: testP256Key},
+	{Method: ES384, Key: testP384Key},
+	{Method: EdDSA, Key: testEd25519Key},
+}
+
+func TestAsymmetric(t *testing.T) {
+	timeFunc = time.Now
+	for _, tc := range asymmetricTestCases {
+		tc := tc
+		t.Run(string(tc.Method), func(t *testing.T) {
+			token, err := Encode("hello", WithSignMethod(tc.Method), WithPrivateKey(tc.Key))
+			require.NoError(t, err)
+
+			var data string
+			require.NoError(t, Decode(token, &data, WithSignMethod(tc.Method), WithPublicKey(tc.Key.Public())))
+			require.Equal(t, "hello", data)
+
+			// Открытый ключ выводится из закрытого
+			require.NoError(t, Decode(token, &data, WithSignMethod(tc.Method), WithPrivateKey(tc.Key)))
+
+			tamper(token)
+			err = Decode(token, &data, WithSignMethod(tc.Method), WithPublicKey(tc.Key.Public()))
+			require.ErrorIs(t, err, ErrSignatureInvalid)
+		})
+	}
+}
+
+func TestAsymmetric_Keys(t *testing.T) {
+	timeFunc = time.Now
+	_, err := Encode("hello", WithSignMethod(RS256), WithPublicKey(testRSAKey.Public()))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+
+	_, err = Encode("hello", WithSignMethod(RS256), WithPrivateKey(testP256Key))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+
+	_, err = Encode("hello", WithSignMethod(ES384), WithPrivateKey(testP256Key))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+
+	_, err = Encode("hello", WithSignMethod(EdDSA), WithKey([]byte("secret")))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+
+	_, err = Encode("hello", WithSignMethod(HS256))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+
+	token, err := Encode("hello", WithSignMethod(ES256), WithPrivateKey(testP256Key))
+	require.NoError(t, err)
+	var data string
+	err = Decode(token, &data, WithSignMethod(ES256), WithPublicKey(testEd25519Key.Public()))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	err = Decode(token, &data, WithSignMethod(ES256))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	err = Decode(token, &data, WithSignMethod(RS256), WithPublicKey(testRSAKey.Public()))
+	require.ErrorIs(t, err, ErrSignMethodMismatched)
+}
+
+// tamper портит один символ подписи токена, оставляя base64 корректным
+func tamper(token []byte) {
+	i := len(token) - 10
+	if token[i] == 'A' {
+		token[i] = 'B'
+	} else {
+		token[i] = 'A'
+	}
+}
@@ -0,0 +1,38 @@
+// Package typed содержит типизированные обертки над Encode и Decode пакета jwt
+package typed
+
+import (
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+// Token - проверенный токен: заголовок, зарегистрированные поля и пользовательские данные
+type Token[T any] struct {
+	Header jwt.Header
+	Claims jwt.Claims
+	Data   T
+}
+
+// Encode создает токен с данными `data`, см. jwt.Encode
+func Encode[T any](data T, opts ...jwt.Option) ([]byte, error) {
+	return jwt.Encode(data, opts...)
+}
+
+// Decode проверяет токен и возвращает его данные, см. jwt.Decode
+func Decode[T any](token []byte, opts ...jwt.Option) (T, error) {
+	var data T
+	if err := jwt.Decode(token, &data, opts...); err != nil {
+		var zero T
+		return zero, err
+	}
+	return data, nil
+}
+
+// DecodeToken проверяет токен и возвращает его вместе с заголовком и зарегистрированными полями
+func DecodeToken[T any](token []byte, opts ...jwt.Option) (*Token[T], error) {
+	res := &Token[T]{}
+	opts = append(opts[:len(opts):len(opts)], jwt.WithHeader(&res.Header), jwt.WithClaims(&res.Claims))
+	if err := jwt.Decode(token, &res.Data, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
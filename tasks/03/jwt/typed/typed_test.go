@@ -0,0 +1,64 @@
+package typed
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+var opts = []jwt.Option{jwt.WithSignMethod(jwt.HS256), jwt.WithKey([]byte("secret-key1"))}
+
+type score struct {
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+}
+
+func TestDecode(t *testing.T) {
+	token, err := Encode(score{Name: "Dmitrii", Score: 42}, opts...)
+	require.NoError(t, err)
+
+	data, err := Decode[score](token, opts...)
+	require.NoError(t, err)
+	require.Equal(t, score{Name: "Dmitrii", Score: 42}, data)
+
+	m, err := Decode[map[string]interface{}](token, opts...)
+	require.NoError(t, err)
+	require.Equal(t, float64(42), m["score"])
+
+	m, err = Decode[map[string]interface{}](token, append(opts, jwt.WithNumberMode(jwt.NumberJSON))...)
+	require.NoError(t, err)
+	require.Equal(t, json.Number("42"), m["score"])
+
+	_, err = Decode[score](token, jwt.WithSignMethod(jwt.HS256), jwt.WithKey([]byte("other")))
+	require.ErrorIs(t, err, jwt.ErrSignatureInvalid)
+	_, err = Decode[int](token, opts...)
+	require.ErrorIs(t, err, jwt.ErrInvalidToken)
+}
+
+func TestDecode_BigNumber(t *testing.T) {
+	token, err := Encode(map[string]uint64{"id": 1<<63 + 1}, opts...)
+	require.NoError(t, err)
+
+	m, err := Decode[map[string]interface{}](token, append(opts, jwt.WithNumberMode(jwt.NumberJSON))...)
+	require.NoError(t, err)
+	require.Equal(t, json.Number("9223372036854775809"), m["id"])
+}
+
+func TestDecodeToken(t *testing.T) {
+	token, err := Encode("hello", append(opts, jwt.WithKeyID("k1"), jwt.WithIssuer("auth"), jwt.WithTTL(time.Hour))...)
+	require.NoError(t, err)
+
+	tok, err := DecodeToken[string](token, opts...)
+	require.NoError(t, err)
+	require.Equal(t, "hello", tok.Data)
+	require.Equal(t, jwt.Header{Alg: jwt.HS256, Typ: "JWT", Kid: "k1"}, tok.Header)
+	require.Equal(t, "auth", tok.Claims.Issuer)
+	require.NotNil(t, tok.Claims.ExpiresAt)
+
+	_, err = DecodeToken[string](token, append(opts, jwt.WithIssuer("other"))...)
+	require.ErrorIs(t, err, jwt.ErrInvalidIssuer)
+}
@@ -0,0 +1,81 @@
+package jwt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUnverified(t *testing.T) {
+	timeFunc = time.Now
+	token, err := Encode("hello", WithSignMethod(ES256), WithPrivateKey(testP256Key), WithKeyID("k1"), WithIssuer("auth"))
+	require.NoError(t, err)
+
+	h, claims, err := ParseUnverified(token)
+	require.NoError(t, err)
+	require.Equal(t, Header{Alg: ES256, Typ: tokenType, Kid: "k1"}, h)
+	require.Equal(t, "auth", claims.Issuer)
+	require.JSONEq(t, `"hello"`, string(claims.Data))
+
+	// Подпись не проверяется
+	tamper(token)
+	_, _, err = ParseUnverified(token)
+	require.NoError(t, err)
+
+	_, _, err = ParseUnverified([]byte("a.b"))
+	require.ErrorIs(t, err, ErrInvalidToken)
+	_, _, err = ParseUnverified([]byte("e30.!!!.c"))
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestKeyFunc(t *testing.T) {
+	timeFunc = time.Now
+	issuers := map[string]JWK{
+		"hmac-issuer": {Algorithm: HS256, Key: []byte("secret")},
+		"ec-issuer":   {Algorithm: ES256, Key: &testP256Key.PublicKey},
+		"rsa-issuer":  {Key: &testRSAKey.PublicKey},
+	}
+	keyFunc := func(h Header, claims RawClaims) (JWK, error) {
+		k, ok := issuers[claims.Issuer]
+		if !ok {
+			return JWK{}, ErrKeyNotFound
+		}
+		return k, nil
+	}
+
+	hmacToken, err := Encode("hello", WithSignMethod(HS256), WithKey([]byte("secret")), WithIssuer("hmac-issuer"))
+	require.NoError(t, err)
+	ecToken, err := Encode("hello", WithSignMethod(ES256), WithPrivateKey(testP256Key), WithIssuer("ec-issuer"))
+	require.NoError(t, err)
+
+	var data string
+	require.NoError(t, Decode(hmacToken, &data, WithKeyFunc(keyFunc)))
+	require.NoError(t, Decode(ecToken, &data, WithKeyFunc(keyFunc)))
+
+	unknown, err := Encode("hello", WithSignMethod(HS256), WithKey([]byte("secret")), WithIssuer("other"))
+	require.NoError(t, err)
+	require.ErrorIs(t, Decode(unknown, &data, WithKeyFunc(keyFunc)), ErrKeyNotFound)
+
+	// Токен подписан открытым RSA ключом как HMAC секретом
+	publicDER := []byte("public key bytes")
+	confused, err := Encode("hello", WithSignMethod(HS256), WithKey(publicDER), WithIssuer("rsa-issuer"))
+	require.NoError(t, err)
+	err = Decode(confused, &data, WithKeyFunc(keyFunc))
+	require.ErrorIs(t, err, ErrInvalidSignMethod)
+	err = Decode(confused, &data, WithKeyFunc(keyFunc), WithSignMethod(RS256))
+	require.ErrorIs(t, err, ErrSignMethodMismatched)
+
+	// Метод ключа не совпадает с заголовком
+	forged, err := Encode("hello", WithSignMethod(HS256), WithKey([]byte("secret")), WithIssuer("ec-issuer"))
+	require.NoError(t, err)
+	require.ErrorIs(t, Decode(forged, &data, WithKeyFunc(keyFunc)), ErrSignMethodMismatched)
+
+	failing := errors.New("lookup failed")
+	err = Decode(hmacToken, &data, WithKeyFunc(func(Header, RawClaims) (JWK, error) { return JWK{}, failing }))
+	require.ErrorIs(t, err, failing)
+
+	err = Decode(hmacToken, &data, WithKeyFunc(keyFunc), WithKeySet(&KeySet{}))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+}
@@ -0,0 +1,185 @@
+// Package jwtauth содержит middleware для аутентификации запросов по JWT
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+// Claims - содержимое проверенного токена: пользовательские данные и зарегистрированные поля
+type Claims[T any] struct {
+	Data T
+	jwt.Claims
+}
+
+type claimsKey[T any] struct{}
+
+func ContextWithClaims[T any](ctx context.Context, claims *Claims[T]) context.Context {
+	return context.WithValue(ctx, claimsKey[T]{}, claims)
+}
+
+// ClaimsFromContext возвращает содержимое токена, сохраненное middleware
+func ClaimsFromContext[T any](ctx context.Context) (*Claims[T], bool) {
+	claims, ok := ctx.Value(claimsKey[T]{}).(*Claims[T])
+	return claims, ok
+}
+
+type Option func(*config)
+
+// WithExtractors задает способы извлечения токена, они пробуются по порядку.
+// По умолчанию токен берется из заголовка Authorization
+func WithExtractors(extractors ...Extractor) Option {
+	return func(c *config) {
+		c.Extractors = extractors
+	}
+}
+
+// WithRealm задает параметр realm заголовка WWW-Authenticate
+func WithRealm(realm string) Option {
+	return func(c *config) {
+		c.Realm = realm
+	}
+}
+
+// WithDecodeOptions задает опции для проверки токена через jwt.Decode
+func WithDecodeOptions(opts ...jwt.Option) Option {
+	return func(c *config) {
+		c.DecodeOptions = opts
+	}
+}
+
+type config struct {
+	Extractors    []Extractor
+	Realm         string
+	DecodeOptions []jwt.Option
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{Extractors: []Extractor{FromAuthorization()}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Authenticate возвращает middleware, которое проверяет токен из запроса и сохраняет
+// его содержимое в контексте (см. ClaimsFromContext). Если токена нет или он
+// недействителен, возвращается 401
+func Authenticate[T any](opts ...Option) func(http.Handler) http.Handler {
+	cfg := newConfig(opts)
+	return func(next http.Handler) http.Handler {
+		fn := func(rw http.ResponseWriter, req *http.Request) {
+			token, ok := cfg.extract(req)
+			if !ok {
+				cfg.fail(rw, http.StatusUnauthorized, "", "")
+				return
+			}
+
+			claims := &Claims[T]{}
+			decodeOpts := append(cfg.DecodeOptions[:len(cfg.DecodeOptions):len(cfg.DecodeOptions)], jwt.WithClaims(&claims.Claims))
+			if err := jwt.Decode(token, &claims.Data, decodeOpts...); err != nil {
+				if errors.Is(err, jwt.ErrConfigurationMalformed) {
+					http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				cfg.fail(rw, http.StatusUnauthorized, "invalid_token", describe(err))
+				return
+			}
+			next.ServeHTTP(rw, req.WithContext(ContextWithClaims(req.Context(), claims)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Authorize возвращает middleware, которое проверяет права по содержимому токена,
+// сохраненному Authenticate. Если `check` возвращает ошибку, запрос отклоняется с кодом 403,
+// текст ошибки клиенту не передается
+func Authorize[T any](check func(req *http.Request, claims *Claims[T]) error, opts ...Option) func(http.Handler) http.Handler {
+	cfg := newConfig(opts)
+	return func(next http.Handler) http.Handler {
+		fn := func(rw http.ResponseWriter, req *http.Request) {
+			claims, ok := ClaimsFromContext[T](req.Context())
+			if !ok {
+				cfg.fail(rw, http.StatusUnauthorized, "", "")
+				return
+			}
+			if err := check(req, claims); err != nil {
+				cfg.fail(rw, http.StatusForbidden, "insufficient_scope", "insufficient scope")
+				return
+			}
+			next.ServeHTTP(rw, req)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func (c *config) extract(req *http.Request) ([]byte, bool) {
+	for _, extract := range c.Extractors {
+		if token, ok := extract(req); ok {
+			return token, true
+		}
+	}
+	return nil, false
+}
+
+// descriptions - тексты error_description для известных ошибок проверки токена.
+// Текст самой ошибки клиенту не отдается: в нем могут быть детали конфигурации
+var descriptions = []struct {
+	err         error
+	description string
+}{
+	{jwt.ErrTokenExpired, "token expired"},
+	{jwt.ErrTokenNotValidYet, "token is not valid yet"},
+	{jwt.ErrTokenUsedBeforeIssued, "token used before issued"},
+	{jwt.ErrTokenRevoked, "token revoked"},
+	{jwt.ErrSignatureInvalid, "invalid signature"},
+	{jwt.ErrSignMethodMismatched, "invalid signature"},
+	{jwt.ErrKeyNotFound, "unknown key"},
+	{jwt.ErrDecryptionFailed, "decryption failed"},
+	{jwt.ErrInvalidIssuer, "invalid issuer"},
+	{jwt.ErrInvalidAudience, "invalid audience"},
+	{jwt.ErrInvalidSubject, "invalid subject"},
+	{jwt.ErrInvalidID, "invalid token id"},
+	{jwt.ErrTokenTooLarge, "token too large"},
+}
+
+// describe возвращает описание ошибки для клиента
+func describe(err error) string {
+	for _, d := range descriptions {
+		if errors.Is(err, d.err) {
+			return d.description
+		}
+	}
+	return "invalid token"
+}
+
+// fail отвечает ошибкой с заголовком WWW-Authenticate по RFC 6750
+func (c *config) fail(rw http.ResponseWriter, code int, reason, description string) {
+	params := make([]string, 0, 3)
+	if c.Realm != "" {
+		params = append(params, fmt.Sprintf("realm=%s", quote(c.Realm)))
+	}
+	if reason != "" {
+		params = append(params, fmt.Sprintf("error=%s", quote(reason)))
+	}
+	if description != "" {
+		params = append(params, fmt.Sprintf("error_description=%s", quote(description)))
+	}
+	challenge := bearerScheme
+	if len(params) != 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+	rw.Header().Set("WWW-Authenticate", challenge)
+	http.Error(rw, http.StatusText(code), code)
+}
+
+var quoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func quote(s string) string {
+	return `"` + quoteReplacer.Replace(s) + `"`
+}
@@ -0,0 +1,182 @@
+package jwtauth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+type user struct {
+	ID    int      `json:"id"`
+	Roles []string `json:"roles"`
+}
+
+var testKey = []byte("secret")
+
+func newToken(t *testing.T, u user, opts ...jwt.Option) string {
+	token, err := jwt.Encode(u, append([]jwt.Option{jwt.WithSignMethod(jwt.HS256), jwt.WithKey(testKey)}, opts...)...)
+	require.NoError(t, err)
+	return string(token)
+}
+
+func newRouter() http.Handler {
+	router := chi.NewRouter()
+	router.Use(Authenticate[user](
+		WithRealm("api"),
+		WithExtractors(FromAuthorization(), FromCookie("token"), FromQuery("access_token")),
+		WithDecodeOptions(jwt.WithSignMethod(jwt.HS256), jwt.WithKey(testKey), jwt.WithIssuer("auth")),
+	))
+	router.Get("/info", func(rw http.ResponseWriter, req *http.Request) {
+		claims, ok := ClaimsFromContext[user](req.Context())
+		if !ok {
+			http.Error(rw, "no claims", http.StatusInternalServerError)
+			return
+		}
+		_, _ = fmt.Fprintf(rw, "Hello, %d from %s", claims.Data.ID, claims.Issuer)
+	})
+	router.With(Authorize(func(req *http.Request, claims *Claims[user]) error {
+		for _, role := range claims.Data.Roles {
+			if role == "admin" {
+				return nil
+			}
+		}
+		return errors.New("admin role required")
+	}, WithRealm("api"))).Get("/admin", func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte("OK"))
+	})
+	return router
+}
+
+func TestAuthenticate(t *testing.T) {
+	router := newRouter()
+	valid := newToken(t, user{ID: 42}, jwt.WithIssuer("auth"))
+	admin := newToken(t, user{ID: 1, Roles: []string{"admin"}}, jwt.WithIssuer("auth"))
+	otherKeyToken, err := jwt.Encode(user{ID: 42}, jwt.WithSignMethod(jwt.HS256), jwt.WithKey([]byte("other")), jwt.WithIssuer("auth"))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		Name      string
+		Path      string
+		Prepare   func(req *http.Request)
+		Code      int
+		Body      string
+		Challenge string
+	}{
+		{
+			Name:    "header",
+			Path:    "/info",
+			Prepare: func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+valid) },
+			Code:    http.StatusOK,
+			Body:    "Hello, 42 from auth",
+		},
+		{
+			Name:    "cookie",
+			Path:    "/info",
+			Prepare: func(req *http.Request) { req.AddCookie(&http.Cookie{Name: "token", Value: valid}) },
+			Code:    http.StatusOK,
+			Body:    "Hello, 42 from auth",
+		},
+		{
+			Name: "query",
+			Path: "/info?access_token=" + valid,
+			Code: http.StatusOK,
+			Body: "Hello, 42 from auth",
+		},
+		{
+			Name:      "no token",
+			Path:      "/info",
+			Code:      http.StatusUnauthorized,
+			Challenge: `Bearer realm="api"`,
+		},
+		{
+			Name:      "wrong scheme",
+			Path:      "/info",
+			Prepare:   func(req *http.Request) { req.Header.Set("Authorization", "Basic "+valid) },
+			Code:      http.StatusUnauthorized,
+			Challenge: `Bearer realm="api"`,
+		},
+		{
+			Name:      "invalid token",
+			Path:      "/info",
+			Prepare:   func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+newToken(t, user{ID: 42})) },
+			Code:      http.StatusUnauthorized,
+			Challenge: `Bearer realm="api", error="invalid_token", error_description="invalid issuer"`,
+		},
+		{
+			Name:      "malformed token",
+			Path:      "/info",
+			Prepare:   func(req *http.Request) { req.Header.Set("Authorization", "Bearer not.a.token") },
+			Code:      http.StatusUnauthorized,
+			Challenge: `Bearer realm="api", error="invalid_token", error_description="invalid token"`,
+		},
+		{
+			Name:      "wrong key",
+			Path:      "/info",
+			Prepare:   func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+string(otherKeyToken)) },
+			Code:      http.StatusUnauthorized,
+			Challenge: `Bearer realm="api", error="invalid_token", error_description="invalid signature"`,
+		},
+		{
+			Name:      "forbidden",
+			Path:      "/admin",
+			Prepare:   func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+valid) },
+			Code:      http.StatusForbidden,
+			Challenge: `Bearer realm="api", error="insufficient_scope", error_description="insufficient scope"`,
+		},
+		{
+			Name:    "admin",
+			Path:    "/admin",
+			Prepare: func(req *http.Request) { req.Header.Set("Authorization", "bearer "+admin) },
+			Code:    http.StatusOK,
+			Body:    "OK",
+		},
+	} {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+			if tc.Prepare != nil {
+				tc.Prepare(req)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.Code, rec.Code)
+			require.Equal(t, tc.Challenge, rec.Header().Get("WWW-Authenticate"))
+			if tc.Body != "" {
+				require.Equal(t, tc.Body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthenticate_Expired(t *testing.T) {
+	token, err := jwt.Encode(user{ID: 1}, jwt.WithSignMethod(jwt.HS256), jwt.WithKey(testKey), jwt.WithExpires(time.Now().Add(time.Second)))
+	require.NoError(t, err)
+
+	handler := Authenticate[user](WithDecodeOptions(jwt.WithSignMethod(jwt.HS256), jwt.WithKey(testKey), jwt.WithLeeway(-2*time.Second)))(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+string(token))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Equal(t, `Bearer error="invalid_token", error_description="token expired"`, rec.Header().Get("WWW-Authenticate"))
+}
+
+func TestAuthenticate_Misconfigured(t *testing.T) {
+	handler := Authenticate[user](WithDecodeOptions(jwt.WithSignMethod(jwt.HS256)))(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+newToken(t, user{}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,50 @@
+package jwtauth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Extractor извлекает токен из запроса. Возвращает false, если токена в запросе нет
+type Extractor func(req *http.Request) ([]byte, bool)
+
+const bearerScheme = "Bearer"
+
+// FromAuthorization извлекает токен из заголовка `Authorization: Bearer <token>`
+func FromAuthorization() Extractor {
+	return func(req *http.Request) ([]byte, bool) {
+		scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, bearerScheme) {
+			return nil, false
+		}
+		token = strings.TrimSpace(token)
+		return []byte(token), token != ""
+	}
+}
+
+// FromHeader извлекает токен из заголовка `name` целиком
+func FromHeader(name string) Extractor {
+	return func(req *http.Request) ([]byte, bool) {
+		token := req.Header.Get(name)
+		return []byte(token), token != ""
+	}
+}
+
+// FromCookie извлекает токен из cookie `name`
+func FromCookie(name string) Extractor {
+	return func(req *http.Request) ([]byte, bool) {
+		cookie, err := req.Cookie(name)
+		if err != nil || cookie.Value == "" {
+			return nil, false
+		}
+		return []byte(cookie.Value), true
+	}
+}
+
+// FromQuery извлекает токен из параметра запроса `name`
+func FromQuery(name string) Extractor {
+	return func(req *http.Request) ([]byte, bool) {
+		token := req.URL.Query().Get(name)
+		return []byte(token), token != ""
+	}
+}
@@ -0,0 +1,108 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"time"
+)
+
+type Option func(*config)
+
+// WithKey задает симметричный ключ для токенов v4.local
+func WithKey(k []byte) Option {
+	return func(c *config) {
+		c.Key = k
+	}
+}
+
+// WithPrivateKey задает ключ для подписи токенов v4.public.
+// При проверке используется соответствующий ему открытый ключ
+func WithPrivateKey(k ed25519.PrivateKey) Option {
+	return func(c *config) {
+		c.PrivateKey = k
+	}
+}
+
+// WithPublicKey задает ключ для проверки токенов v4.public
+func WithPublicKey(k ed25519.PublicKey) Option {
+	return func(c *config) {
+		c.PublicKey = k
+	}
+}
+
+// WithFooter задает footer токена. Footer не шифруется, но защищен от изменений.
+// При проверке footer токена должен совпадать
+func WithFooter(f []byte) Option {
+	return func(c *config) {
+		c.Footer = f
+	}
+}
+
+// WithImplicitAssertion задает неявное утверждение: данные, которые не хранятся в токене,
+// но участвуют в его аутентификации и должны совпадать при проверке
+func WithImplicitAssertion(i []byte) Option {
+	return func(c *config) {
+		c.Implicit = i
+	}
+}
+
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.TTL = &ttl
+	}
+}
+
+func WithExpires(t time.Time) Option {
+	return func(c *config) {
+		c.Expires = &t
+	}
+}
+
+// WithIssuer задает издателя токена (`iss`). При проверке издатель токена должен совпадать
+func WithIssuer(iss string) Option {
+	return func(c *config) {
+		c.Issuer = iss
+	}
+}
+
+// WithSubject задает субъекта токена (`sub`). При проверке субъект токена должен совпадать
+func WithSubject(sub string) Option {
+	return func(c *config) {
+		c.Subject = sub
+	}
+}
+
+// WithAudience задает получателя токена (`aud`). При проверке получатель токена должен совпадать
+func WithAudience(aud string) Option {
+	return func(c *config) {
+		c.Audience = aud
+	}
+}
+
+type config struct {
+	Key        []byte
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+	Footer     []byte
+	Implicit   []byte
+	TTL        *time.Duration
+	Expires    *time.Time
+	Issuer     string
+	Subject    string
+	Audience   string
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// publicKey возвращает ключ для проверки подписи
+func (c *config) publicKey() ed25519.PublicKey {
+	if c.PublicKey == nil && len(c.PrivateKey) == ed25519.PrivateKeySize {
+		return c.PrivateKey.Public().(ed25519.PublicKey)
+	}
+	return c.PublicKey
+}
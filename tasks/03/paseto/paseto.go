@@ -0,0 +1,284 @@
+// Package paseto реализует токены PASETO v4: v4.local (XChaCha20 + BLAKE2b)
+// и v4.public (Ed25519). В отличие от JWT, алгоритм определяется версией
+// и назначением токена, а не заголовком, который можно подменить
+package paseto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dbeliakov/mipt-golang-course/tasks/03/jwt"
+)
+
+// Ошибки совпадают с ошибками пакета jwt, чтобы их можно было обрабатывать одинаково
+var (
+	ErrInvalidToken           = jwt.ErrInvalidToken
+	ErrSignatureInvalid       = jwt.ErrSignatureInvalid
+	ErrSignMethodMismatched   = jwt.ErrSignMethodMismatched
+	ErrConfigurationMalformed = jwt.ErrConfigurationMalformed
+	ErrTokenExpired           = jwt.ErrTokenExpired
+	ErrTokenNotValidYet       = jwt.ErrTokenNotValidYet
+	ErrInvalidIssuer          = jwt.ErrInvalidIssuer
+	ErrInvalidSubject         = jwt.ErrInvalidSubject
+	ErrInvalidAudience        = jwt.ErrInvalidAudience
+)
+
+var (
+	encoding  = base64.RawURLEncoding.Strict()
+	separator = []byte{'.'}
+)
+
+// claims - зарегистрированные поля PASETO, время хранится в формате RFC 3339
+type claims struct {
+	Issuer    string     `json:"iss,omitempty"`
+	Subject   string     `json:"sub,omitempty"`
+	Audience  string     `json:"aud,omitempty"`
+	ExpiresAt *time.Time `json:"exp,omitempty"`
+	NotBefore *time.Time `json:"nbf,omitempty"`
+	IssuedAt  *time.Time `json:"iat,omitempty"`
+	ID        string     `json:"jti,omitempty"`
+}
+
+var registeredClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+// Encode создает токен с данными `data` (структурой или map), поля которых записываются
+// рядом с зарегистрированными. С ключом из WithKey создается токен v4.local,
+// с ключом из WithPrivateKey - v4.public
+func Encode(data interface{}, opts ...Option) ([]byte, error) {
+	cfg := newConfig(opts)
+	message, err := cfg.marshalPayload(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var header string
+	var body []byte
+	switch {
+	case cfg.Key != nil && cfg.PrivateKey != nil:
+		return nil, fmt.Errorf("%w: both local and public keys are set", ErrConfigurationMalformed)
+	case cfg.Key != nil:
+		if len(cfg.Key) != KeySize {
+			return nil, fmt.Errorf("%w: local key must be %d bytes", ErrConfigurationMalformed, KeySize)
+		}
+		nonce := make([]byte, nonceSize)
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, err
+		}
+		header = headerLocal
+		if body, err = encrypt(cfg.Key, nonce, message, cfg.Footer, cfg.Implicit); err != nil {
+			return nil, err
+		}
+	case cfg.PrivateKey != nil:
+		if len(cfg.PrivateKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("%w: invalid ed25519 private key", ErrConfigurationMalformed)
+		}
+		header = headerPublic
+		body = sign(cfg.PrivateKey, message, cfg.Footer, cfg.Implicit)
+	default:
+		return nil, fmt.Errorf("%w: key is not set", ErrConfigurationMalformed)
+	}
+	return formatToken(header, body, cfg.Footer), nil
+}
+
+// Decode проверяет токен и заполняет `data`. Назначение токена определяется
+// ключом: WithKey для v4.local, WithPublicKey или WithPrivateKey для v4.public
+func Decode(token []byte, data interface{}, opts ...Option) error {
+	cfg := newConfig(opts)
+	var header string
+	switch {
+	case cfg.Key != nil && cfg.publicKey() != nil:
+		return fmt.Errorf("%w: both local and public keys are set", ErrConfigurationMalformed)
+	case cfg.Key != nil:
+		if len(cfg.Key) != KeySize {
+			return fmt.Errorf("%w: local key must be %d bytes", ErrConfigurationMalformed, KeySize)
+		}
+		header = headerLocal
+	case cfg.publicKey() != nil:
+		if len(cfg.publicKey()) != ed25519.PublicKeySize {
+			return fmt.Errorf("%w: invalid ed25519 public key", ErrConfigurationMalformed)
+		}
+		header = headerPublic
+	default:
+		return fmt.Errorf("%w: key is not set", ErrConfigurationMalformed)
+	}
+
+	if !bytes.HasPrefix(token, []byte(header)) {
+		if bytes.HasPrefix(token, []byte(headerLocal)) || bytes.HasPrefix(token, []byte(headerPublic)) {
+			return fmt.Errorf("%w: expected %s token", ErrSignMethodMismatched, header)
+		}
+		return fmt.Errorf("%w: unsupported header", ErrInvalidToken)
+	}
+	body, footer, err := parseToken(token[len(header):])
+	if err != nil {
+		return err
+	}
+	if cfg.Footer != nil && subtle.ConstantTimeCompare(cfg.Footer, footer) != 1 {
+		return fmt.Errorf("%w: footer mismatch", ErrInvalidToken)
+	}
+
+	var message []byte
+	if header == headerLocal {
+		message, err = decrypt(cfg.Key, body, footer, cfg.Implicit)
+	} else {
+		message, err = verify(cfg.publicKey(), body, footer, cfg.Implicit)
+	}
+	if err != nil {
+		return err
+	}
+	return cfg.unmarshalPayload(message, data)
+}
+
+// Footer возвращает footer токена без проверки. Его можно использовать, например,
+// для выбора ключа по идентификатору
+func Footer(token []byte) ([]byte, error) {
+	for _, header := range []string{headerLocal, headerPublic} {
+		if bytes.HasPrefix(token, []byte(header)) {
+			_, footer, err := parseToken(token[len(header):])
+			return footer, err
+		}
+	}
+	return nil, fmt.Errorf("%w: unsupported header", ErrInvalidToken)
+}
+
+func formatToken(header string, body, footer []byte) []byte {
+	size := len(header) + encoding.EncodedLen(len(body))
+	if len(footer) != 0 {
+		size += len(separator) + encoding.EncodedLen(len(footer))
+	}
+	token := make([]byte, size)
+	n := copy(token, header)
+	encoding.Encode(token[n:], body)
+	if len(footer) != 0 {
+		n += encoding.EncodedLen(len(body))
+		n += copy(token[n:], separator)
+		encoding.Encode(token[n:], footer)
+	}
+	return token
+}
+
+// parseToken разбирает токен без заголовка на тело и footer
+func parseToken(token []byte) ([]byte, []byte, error) {
+	parts := bytes.Split(token, separator)
+	if len(parts) > 2 {
+		return nil, nil, fmt.Errorf("%w: expected at most 2 parts after header, got %d", ErrInvalidToken, len(parts))
+	}
+	decoded := make([][]byte, 2)
+	for i, part := range parts {
+		raw := make([]byte, encoding.DecodedLen(len(part)))
+		n, err := encoding.Decode(raw, part)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		}
+		decoded[i] = raw[:n]
+	}
+	if len(parts) == 2 && len(decoded[1]) == 0 {
+		return nil, nil, fmt.Errorf("%w: empty footer", ErrInvalidToken)
+	}
+	return decoded[0], decoded[1], nil
+}
+
+// marshalPayload объединяет поля `data` с зарегистрированными полями
+func (c *config) marshalPayload(data interface{}) ([]byte, error) {
+	registered, err := c.claims()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("%w: payload must be an object, got %T", ErrConfigurationMalformed, data)
+	}
+	if raw, err = json.Marshal(registered); err != nil {
+		return nil, err
+	}
+	var claimFields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &claimFields); err != nil {
+		return nil, err
+	}
+	for name, value := range claimFields {
+		if _, ok := fields[name]; ok {
+			return nil, fmt.Errorf("%w: claim %q is set twice", ErrConfigurationMalformed, name)
+		}
+		fields[name] = value
+	}
+	return json.Marshal(fields)
+}
+
+// unmarshalPayload проверяет зарегистрированные поля и заполняет `data` остальными
+func (c *config) unmarshalPayload(message []byte, data interface{}) error {
+	var registered claims
+	if err := json.Unmarshal(message, &registered); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err := c.validate(&registered); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(message, &fields); err != nil || fields == nil {
+		return fmt.Errorf("%w: payload is not an object", ErrInvalidToken)
+	}
+	for _, name := range registeredClaims {
+		delete(fields, name)
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, data); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+func (c *config) claims() (claims, error) {
+	res := claims{Issuer: c.Issuer, Subject: c.Subject, Audience: c.Audience}
+	now := timeFunc()
+	switch {
+	case c.TTL != nil && c.Expires != nil:
+		return res, ErrConfigurationMalformed
+	case c.TTL != nil:
+		exp := now.Add(*c.TTL).UTC().Truncate(time.Second)
+		res.ExpiresAt = &exp
+	case c.Expires != nil:
+		if c.Expires.Before(now) {
+			return res, ErrConfigurationMalformed
+		}
+		exp := c.Expires.UTC().Truncate(time.Second)
+		res.ExpiresAt = &exp
+	}
+	return res, nil
+}
+
+func (c *config) validate(registered *claims) error {
+	now := timeFunc()
+	if registered.ExpiresAt != nil && !now.Before(*registered.ExpiresAt) {
+		return ErrTokenExpired
+	}
+	if registered.NotBefore != nil && now.Before(*registered.NotBefore) {
+		return ErrTokenNotValidYet
+	}
+	if c.Issuer != "" && registered.Issuer != c.Issuer {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, registered.Issuer)
+	}
+	if c.Subject != "" && registered.Subject != c.Subject {
+		return fmt.Errorf("%w: %q", ErrInvalidSubject, registered.Subject)
+	}
+	if c.Audience != "" && registered.Audience != c.Audience {
+		return fmt.Errorf("%w: %q", ErrInvalidAudience, registered.Audience)
+	}
+	return nil
+}
+
+// To mock time in tests
+var timeFunc = time.Now
@@ -0,0 +1,194 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testVector struct {
+	Name       string `json:"name"`
+	ExpectFail bool   `json:"expect-fail"`
+	Key        string `json:"key"`
+	Nonce      string `json:"nonce"`
+	PublicKey  string `json:"public-key"`
+	SecretKey  string `json:"secret-key"`
+	Token      string `json:"token"`
+	Payload    string `json:"payload"`
+	Footer     string `json:"footer"`
+	Implicit   string `json:"implicit-assertion"`
+}
+
+func loadVectors(t *testing.T) []testVector {
+	raw, err := os.ReadFile("testdata/v4.json")
+	require.NoError(t, err)
+	var vectors struct {
+		Tests []testVector `json:"tests"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &vectors))
+	return vectors.Tests
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	raw, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestVectors(t *testing.T) {
+	// Векторы истекают 2022-01-01
+	timeFunc = func() time.Time { return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC) }
+	defer func() { timeFunc = time.Now }()
+
+	for _, tc := range loadVectors(t) {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			footer, implicit := []byte(tc.Footer), []byte(tc.Implicit)
+			if tc.ExpectFail {
+				// Токен другой версии или назначения, с ключом не того типа или с неканоническим base64
+				opts := []Option{WithFooter(footer), WithImplicitAssertion(implicit)}
+				if tc.Key != "" {
+					opts = append(opts, WithKey(mustHex(t, tc.Key)))
+				}
+				if tc.PublicKey != "" {
+					opts = append(opts, WithPublicKey(mustHex(t, tc.PublicKey)))
+				}
+				var data interface{}
+				require.Error(t, Decode([]byte(tc.Token), &data, opts...))
+				return
+			}
+
+			var opts []Option
+			if strings.HasPrefix(tc.Token, headerLocal) {
+				key := mustHex(t, tc.Key)
+				body, err := encrypt(key, mustHex(t, tc.Nonce), []byte(tc.Payload), footer, implicit)
+				require.NoError(t, err)
+				require.Equal(t, tc.Token, string(formatToken(headerLocal, body, footer)))
+				opts = append(opts, WithKey(key))
+			} else {
+				body := sign(ed25519.PrivateKey(mustHex(t, tc.SecretKey)), []byte(tc.Payload), footer, implicit)
+				require.Equal(t, tc.Token, string(formatToken(headerPublic, body, footer)))
+				opts = append(opts, WithPublicKey(mustHex(t, tc.PublicKey)))
+			}
+
+			var data struct {
+				Data string `json:"data"`
+			}
+			opts = append(opts, WithFooter(footer), WithImplicitAssertion(implicit))
+			require.NoError(t, Decode([]byte(tc.Token), &data, opts...))
+			require.Contains(t, tc.Payload, data.Data)
+
+			got, err := Footer([]byte(tc.Token))
+			require.NoError(t, err)
+			require.Equal(t, tc.Footer, string(got))
+		})
+	}
+}
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestLocal(t *testing.T) {
+	timeFunc = time.Now
+	key := make([]byte, KeySize)
+	_, _ = rand.Read(key)
+	opts := []Option{WithKey(key), WithFooter([]byte("kid-1")), WithImplicitAssertion([]byte("tenant-1"))}
+
+	token, err := Encode(user{ID: 42, Name: "Ivan"}, append(opts, WithTTL(time.Hour), WithIssuer("auth"))...)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(token), headerLocal))
+	require.NotContains(t, string(token), "eyJ")
+
+	var u user
+	require.NoError(t, Decode(token, &u, append(opts, WithIssuer("auth"))...))
+	require.Equal(t, user{ID: 42, Name: "Ivan"}, u)
+
+	err = Decode(token, &u, WithKey(key), WithFooter([]byte("kid-1")), WithImplicitAssertion([]byte("tenant-2")))
+	require.ErrorIs(t, err, ErrSignatureInvalid)
+	err = Decode(token, &u, WithKey(key), WithFooter([]byte("kid-2")), WithImplicitAssertion([]byte("tenant-1")))
+	require.ErrorIs(t, err, ErrInvalidToken)
+	err = Decode(token, &u, append(opts, WithIssuer("other"))...)
+	require.ErrorIs(t, err, ErrInvalidIssuer)
+
+	other := make([]byte, KeySize)
+	err = Decode(token, &u, WithKey(other), WithImplicitAssertion([]byte("tenant-1")))
+	require.ErrorIs(t, err, ErrSignatureInvalid)
+
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	err = Decode(token, &u, WithPublicKey(public))
+	require.ErrorIs(t, err, ErrSignMethodMismatched)
+}
+
+func TestPublic(t *testing.T) {
+	timeFunc = time.Now
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	token, err := Encode(map[string]string{"role": "admin"}, WithPrivateKey(private), WithAudience("api"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(token), headerPublic))
+
+	var data map[string]string
+	require.NoError(t, Decode(token, &data, WithPublicKey(public), WithAudience("api")))
+	require.Equal(t, map[string]string{"role": "admin"}, data)
+
+	err = Decode(token, &data, WithPublicKey(public), WithAudience("web"))
+	require.ErrorIs(t, err, ErrInvalidAudience)
+	err = Decode(token, &data, WithKey(make([]byte, KeySize)))
+	require.ErrorIs(t, err, ErrSignMethodMismatched)
+
+	token[len(headerPublic)+3] ^= 1
+	err = Decode(token, &data, WithPublicKey(public))
+	require.Error(t, err)
+}
+
+func TestExpiration(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+	key := make([]byte, KeySize)
+
+	token, err := Encode(user{}, WithKey(key), WithTTL(time.Minute))
+	require.NoError(t, err)
+	var u user
+	require.NoError(t, Decode(token, &u, WithKey(key)))
+
+	now = now.Add(time.Minute)
+	require.ErrorIs(t, Decode(token, &u, WithKey(key)), ErrTokenExpired)
+
+	_, err = Encode(user{}, WithKey(key), WithExpires(now.Add(-time.Second)))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	_, err = Encode(user{}, WithKey(key), WithExpires(now.Add(time.Second)), WithTTL(time.Second))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+}
+
+func TestErrors(t *testing.T) {
+	key := make([]byte, KeySize)
+	_, err := Encode(user{}, WithKey(key[:16]))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	_, err = Encode("scalar", WithKey(key))
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+	_, err = Encode(user{})
+	require.ErrorIs(t, err, ErrConfigurationMalformed)
+
+	var u user
+	for _, token := range []string{
+		"v3.local.AAAA",
+		"v4.local.",
+		"v4.local.AAAA",
+		"v4.local.!!!",
+		"v4.local.AAAA.AAAA.AAAA",
+	} {
+		require.ErrorIs(t, Decode([]byte(token), &u, WithKey(key)), ErrInvalidToken, token)
+	}
+}
@@ -0,0 +1,136 @@
+package paseto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	headerLocal  = "v4.local."
+	headerPublic = "v4.public."
+
+	KeySize   = 32
+	nonceSize = 32
+	macSize   = 32
+)
+
+var (
+	encryptionKeyInfo = []byte("paseto-encryption-key")
+	authKeyInfo       = []byte("paseto-auth-key-for-aead")
+)
+
+// pae - Pre-Authentication Encoding: количество частей и длина каждой части
+// в виде 64-битных little-endian чисел со сброшенным старшим битом
+func pae(pieces ...[]byte) []byte {
+	var b bytes.Buffer
+	writeLE64(&b, len(pieces))
+	for _, piece := range pieces {
+		writeLE64(&b, len(piece))
+		b.Write(piece)
+	}
+	return b.Bytes()
+}
+
+func writeLE64(b *bytes.Buffer, n int) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(n)&(1<<63-1))
+	b.Write(buf[:])
+}
+
+// deriveKeys выводит ключ шифрования, nonce XChaCha20 и ключ аутентификации
+func deriveKeys(key, nonce []byte) (ek, n2, ak []byte, err error) {
+	h, err := blake2b.New(chacha20.KeySize+chacha20.NonceSizeX, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	h.Write(encryptionKeyInfo)
+	h.Write(nonce)
+	tmp := h.Sum(nil)
+
+	h, err = blake2b.New(macSize, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	h.Write(authKeyInfo)
+	h.Write(nonce)
+	return tmp[:chacha20.KeySize], tmp[chacha20.KeySize:], h.Sum(nil), nil
+}
+
+func mac(ak []byte, preAuth []byte) ([]byte, error) {
+	h, err := blake2b.New(macSize, ak)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(preAuth)
+	return h.Sum(nil), nil
+}
+
+// encrypt возвращает тело токена v4.local: nonce || шифротекст || MAC
+func encrypt(key, nonce, message, footer, implicit []byte) ([]byte, error) {
+	ek, n2, ak, err := deriveKeys(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	c, err := chacha20.NewUnauthenticatedCipher(ek, n2)
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, nonceSize+len(message), nonceSize+len(message)+macSize)
+	copy(body, nonce)
+	c.XORKeyStream(body[nonceSize:], message)
+
+	t, err := mac(ak, pae([]byte(headerLocal), nonce, body[nonceSize:], footer, implicit))
+	if err != nil {
+		return nil, err
+	}
+	return append(body, t...), nil
+}
+
+func decrypt(key, body, footer, implicit []byte) ([]byte, error) {
+	if len(body) < nonceSize+macSize {
+		return nil, fmt.Errorf("%w: token is too short", ErrInvalidToken)
+	}
+	nonce, ciphertext, t := body[:nonceSize], body[nonceSize:len(body)-macSize], body[len(body)-macSize:]
+	ek, n2, ak, err := deriveKeys(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := mac(ak, pae([]byte(headerLocal), nonce, ciphertext, footer, implicit))
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(expected, t) != 1 {
+		return nil, ErrSignatureInvalid
+	}
+	c, err := chacha20.NewUnauthenticatedCipher(ek, n2)
+	if err != nil {
+		return nil, err
+	}
+	message := make([]byte, len(ciphertext))
+	c.XORKeyStream(message, ciphertext)
+	return message, nil
+}
+
+// sign возвращает тело токена v4.public: сообщение || подпись
+func sign(key ed25519.PrivateKey, message, footer, implicit []byte) []byte {
+	signature := ed25519.Sign(key, pae([]byte(headerPublic), message, footer, implicit))
+	body := make([]byte, 0, len(message)+ed25519.SignatureSize)
+	return append(append(body, message...), signature...)
+}
+
+func verify(key ed25519.PublicKey, body, footer, implicit []byte) ([]byte, error) {
+	if len(body) < ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: token is too short", ErrInvalidToken)
+	}
+	message, signature := body[:len(body)-ed25519.SignatureSize], body[len(body)-ed25519.SignatureSize:]
+	if !ed25519.Verify(key, pae([]byte(headerPublic), message, footer, implicit), signature) {
+		return nil, ErrSignatureInvalid
+	}
+	return message, nil
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// panicRecord - запись о панике для WriterHook
+type panicRecord struct {
+	Time           string `json:"time"`
+	Method         string `json:"method"`
+	URL            string `json:"url"`
+	Panic          string `json:"panic"`
+	Stack          string `json:"stack"`
+	HeadersWritten bool   `json:"headers_written"`
+}
+
+// WriterHook пишет каждую панику в `w` строкой json, например, в файл
+func WriterHook(w io.Writer) PanicHook {
+	var mutex sync.Mutex
+	return func(p Panic) error {
+		raw, err := json.Marshal(panicRecord{
+			Time:           p.Time.UTC().Format(time.RFC3339Nano),
+			Method:         p.Request.Method,
+			URL:            p.Request.URL.String(),
+			Panic:          formatValue(p.Value),
+			Stack:          string(p.Stack),
+			HeadersWritten: p.HeadersWritten,
+		})
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		_, err = w.Write(append(raw, '\n'))
+		return err
+	}
+}
+
+// CrashDumpHook сохраняет каждую панику в отдельный файл каталога `dir`
+func CrashDumpHook(dir string) PanicHook {
+	var counter uint64
+	return func(p Panic) error {
+		n := atomic.AddUint64(&counter, 1)
+		name := fmt.Sprintf("panic-%s-%d.txt", p.Time.UTC().Format("20060102T150405.000000000"), n)
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(f, "%s %s\npanic: %s\n\n%s", p.Request.Method, p.Request.URL, formatValue(p.Value), p.Stack)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+}
+
+func formatValue(v interface{}) string {
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testPanic() Panic {
+	return Panic{
+		Value:   "boom",
+		Stack:   []byte("goroutine 1 [running]:\n"),
+		Request: httptest.NewRequest(http.MethodGet, "/path?q=1", nil),
+		Time:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestWriterHook(t *testing.T) {
+	var b bytes.Buffer
+	hook := WriterHook(&b)
+	require.NoError(t, hook(testPanic()))
+	require.NoError(t, hook(testPanic()))
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	require.Equal(t, map[string]interface{}{
+		"time":            "2020-01-02T03:04:05Z",
+		"method":          "GET",
+		"url":             "/path?q=1",
+		"panic":           "boom",
+		"stack":           "goroutine 1 [running]:\n",
+		"headers_written": false,
+	}, record)
+}
+
+func TestCrashDumpHook(t *testing.T) {
+	dir := t.TempDir()
+	hook := CrashDumpHook(dir)
+	require.NoError(t, hook(testPanic()))
+	require.NoError(t, hook(testPanic()))
+
+	files, err := filepath.Glob(filepath.Join(dir, "panic-*.txt"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	data, err := ioutil.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Equal(t, "GET /path?q=1\npanic: boom\n\ngoroutine 1 [running]:\n", string(data))
+
+	require.Error(t, CrashDumpHook(filepath.Join(dir, "missing"))(testPanic()))
+}
@@ -1,28 +1,198 @@
 package middleware
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"log"
+	"mime"
+	"net"
 	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
 )
 
+// Panic - информация о панике, пойманной при обработке запроса
+type Panic struct {
+	Value   interface{}
+	Stack   []byte
+	Request *http.Request
+	Time    time.Time
+	// HeadersWritten - обработчик успел отправить заголовки ответа, поэтому
+	// вместо ответа с ошибкой соединение разрывается
+	HeadersWritten bool
+}
+
+// PanicHook вызывается для каждой пойманной паники, например, чтобы отправить ее в систему
+// сбора ошибок. Ошибки хуков пишутся в лог
+type PanicHook func(p Panic) error
+
+type Option func(*RecoverMiddleware)
+
+// WithHook добавляет хук, хуки вызываются в порядке добавления
+func WithHook(hook PanicHook) Option {
+	return func(recMware *RecoverMiddleware) {
+		recMware.hooks = append(recMware.hooks, hook)
+	}
+}
+
 type RecoverMiddleware struct {
 	handler *http.Handler
 	logger  *log.Logger
+	hooks   []PanicHook
 }
 
 func (recMware RecoverMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	rw := &responseWriter{ResponseWriter: writer}
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+		// net/http ожидает эту панику для тихого разрыва соединения
+		if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+			panic(rec)
+		}
+
+		p := Panic{Value: rec, Stack: debug.Stack(), Request: request, Time: time.Now(), HeadersWritten: rw.wroteHeader}
+		// Формат строки лога фиксирован, стек получают только хуки
+		recMware.logger.Printf("[ERROR] Panic caught: %v", rec)
+		recMware.runHooks(p)
+		if rw.wroteHeader {
+			// Второй WriteHeader ничего не исправит, клиент получит обрезанный ответ
+			panic(http.ErrAbortHandler)
+		}
+		writeError(writer, request)
+	}()
+	(*(recMware.handler)).ServeHTTP(rw, request)
+}
+
+func (recMware RecoverMiddleware) runHooks(p Panic) {
+	for _, hook := range recMware.hooks {
+		if err := callHook(hook, p); err != nil {
+			recMware.logger.Printf("[ERROR] Panic hook failed: %v", err)
+		}
+	}
+}
+
+// callHook вызывает хук, превращая его собственную панику в ошибку
+func callHook(hook PanicHook, p Panic) (err error) {
 	defer func() {
 		if rec := recover(); rec != nil {
-			recMware.logger.Printf("[ERROR] Panic caught: %v", rec)
-			writer.WriteHeader(http.StatusInternalServerError)
-			writer.Write([]byte("Internal Server Error\n"))
+			err = &hookPanicError{value: rec}
 		}
 	}()
-	(*(recMware.handler)).ServeHTTP(writer, request)
+	return hook(p)
 }
 
-func Recover(logger *log.Logger) func(http.Handler) http.Handler {
+type hookPanicError struct {
+	value interface{}
+}
+
+func (e *hookPanicError) Error() string {
+	return "hook panicked: " + formatValue(e.value)
+}
+
+// Recover перехватывает паники обработчика. В `logger` пишется только значение паники,
+// стек передается хукам из WithHook
+func Recover(logger *log.Logger, opts ...Option) func(http.Handler) http.Handler {
 	return func(handler http.Handler) http.Handler {
-		return RecoverMiddleware{handler: &handler, logger: logger}
+		recMware := RecoverMiddleware{handler: &handler, logger: logger}
+		for _, opt := range opts {
+			opt(&recMware)
+		}
+		return recMware
 	}
 }
+
+const (
+	contentTypeText    = "text/plain; charset=utf-8"
+	contentTypeJSON    = "application/json"
+	contentTypeProblem = "application/problem+json"
+)
+
+// problem - тело ответа в формате RFC 7807
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+}
+
+// writeError пишет ответ 500 в формате, который клиент указал в Accept
+func writeError(writer http.ResponseWriter, request *http.Request) {
+	status := http.StatusInternalServerError
+	contentType := negotiate(request.Header.Values("Accept"))
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	writer.WriteHeader(status)
+	if contentType == contentTypeText {
+		_, _ = writer.Write([]byte(http.StatusText(status) + "\n"))
+		return
+	}
+	_ = json.NewEncoder(writer).Encode(problem{Type: "about:blank", Title: http.StatusText(status), Status: status})
+}
+
+// negotiate выбирает формат ответа по заголовкам Accept. Предпочтение отдается
+// problem+json, затем json, по умолчанию используется текст
+func negotiate(accept []string) string {
+	var jsonAccepted bool
+	for _, header := range accept {
+		for _, part := range strings.Split(header, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil || params["q"] == "0" {
+				continue
+			}
+			switch mediaType {
+			case contentTypeProblem:
+				return contentTypeProblem
+			case contentTypeJSON:
+				jsonAccepted = true
+			}
+		}
+	}
+	if jsonAccepted {
+		return contentTypeJSON
+	}
+	return contentTypeText
+}
+
+// responseWriter запоминает, были ли отправлены заголовки ответа
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	// Информационные ответы (1xx) не завершают отправку заголовков
+	if status >= http.StatusOK || status == http.StatusSwitchingProtocols {
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(data []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(data)
+}
+
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.wroteHeader = true
+		f.Flush()
+	}
+}
+
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	rw.wroteHeader = true
+	return h.Hijack()
+}
+
+// Unwrap нужен http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -90,3 +92,111 @@ func fetch(t *testing.T, c *http.Client, u string) (int, string) {
 
 	return resp.StatusCode, string(data)
 }
+
+func TestRecover_Accept(t *testing.T) {
+	handler := Recover(log.New(ioutil.Discard, "", 0))(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	}))
+
+	for _, tc := range []struct {
+		Accept      string
+		ContentType string
+	}{
+		{Accept: "", ContentType: "text/plain; charset=utf-8"},
+		{Accept: "text/html, */*", ContentType: "text/plain; charset=utf-8"},
+		{Accept: "application/json", ContentType: "application/json"},
+		{Accept: "text/html, application/problem+json;q=0.9, application/json", ContentType: "application/problem+json"},
+		{Accept: "application/problem+json;q=0, application/json", ContentType: "application/json"},
+	} {
+		tc := tc
+		t.Run(tc.Accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.Accept != "" {
+				req.Header.Set("Accept", tc.Accept)
+			}
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+			require.Equal(t, http.StatusInternalServerError, rw.Code)
+			require.Equal(t, tc.ContentType, rw.Header().Get("Content-Type"))
+			if tc.ContentType != "text/plain; charset=utf-8" {
+				require.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500}`, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestRecover_Hooks(t *testing.T) {
+	var b bytes.Buffer
+	logger := log.New(&b, "", 0)
+
+	var caught []Panic
+	handler := Recover(logger,
+		WithHook(func(p Panic) error {
+			caught = append(caught, p)
+			return nil
+		}),
+		WithHook(func(p Panic) error {
+			return errors.New("sink unavailable")
+		}),
+		WithHook(func(p Panic) error {
+			panic("hook")
+		}),
+	)(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		panic(fmt.Errorf("failed %d", 42))
+	}))
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/path", nil))
+	require.Equal(t, http.StatusInternalServerError, rw.Code)
+
+	require.Len(t, caught, 1)
+	require.EqualError(t, caught[0].Value.(error), "failed 42")
+	require.Equal(t, "/path", caught[0].Request.URL.Path)
+	require.False(t, caught[0].HeadersWritten)
+	require.Contains(t, string(caught[0].Stack), "TestRecover_Hooks")
+	require.Equal(t, "[ERROR] Panic caught: failed 42\n"+
+		"[ERROR] Panic hook failed: sink unavailable\n"+
+		"[ERROR] Panic hook failed: hook panicked: hook\n", b.String())
+}
+
+func TestRecover_Abort(t *testing.T) {
+	var b bytes.Buffer
+	logger := log.New(&b, "", 0)
+	caught := make(chan Panic, 1)
+	hook := WithHook(func(p Panic) error {
+		caught <- p
+		return nil
+	})
+
+	router := chi.NewRouter()
+	router.Use(Recover(logger, hook))
+	router.Get("/abort", func(rw http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+	router.Get("/partial", func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+		_, _ = rw.Write([]byte("partial"))
+		rw.(http.Flusher).Flush()
+		panic("after headers")
+	})
+
+	s := httptest.NewServer(router)
+	defer s.Close()
+	c := s.Client()
+
+	// ErrAbortHandler пробрасывается дальше без логирования
+	_, err := c.Get(s.URL + "/abort")
+	require.Error(t, err)
+	require.Empty(t, caught)
+
+	// Заголовки уже отправлены: соединение разрывается, ответ обрезан
+	resp, err := c.Get(s.URL + "/partial")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.Error(t, err)
+	p := <-caught
+	require.True(t, p.HeadersWritten)
+	require.Equal(t, "[ERROR] Panic caught: after headers\n", b.String())
+}
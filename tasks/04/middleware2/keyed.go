@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"container/list"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyFunc выбирает раздел, в котором учитывается запрос. Пустой ключ означает,
+// что ключ определить не удалось
+type KeyFunc func(request *http.Request) string
+
+// defaultIPv6Prefix - длина префикса, по которому группируются адреса IPv6: клиент
+// обычно получает целую подсеть /64 и может менять адреса внутри нее
+const defaultIPv6Prefix = 64
+
+// ClientIP выбирает раздел по адресу клиента. Если запрос пришел от доверенного прокси
+// из `trusted` (адреса или подсети в нотации CIDR), адрес клиента берется из X-Forwarded-For:
+// первый справа адрес, не принадлежащий доверенным прокси. Адреса IPv6 группируются по подсети /64
+func ClientIP(trusted ...string) (KeyFunc, error) {
+	return ClientIPPrefix(defaultIPv6Prefix, trusted...)
+}
+
+// ClientIPPrefix работает как ClientIP, но группирует адреса IPv6 по подсети
+// с префиксом длины `ipv6Prefix`, 128 - без группировки
+func ClientIPPrefix(ipv6Prefix int, trusted ...string) (KeyFunc, error) {
+	if ipv6Prefix < 0 || ipv6Prefix > 8*net.IPv6len {
+		return nil, fmt.Errorf("invalid IPv6 prefix length %d", ipv6Prefix)
+	}
+	mask := net.CIDRMask(ipv6Prefix, 8*net.IPv6len)
+	nets := make([]*net.IPNet, 0, len(trusted))
+	for _, s := range trusted {
+		// Отдельный адрес - подсеть из одного адреса
+		if !strings.Contains(s, "/") {
+			if strings.Contains(s, ":") {
+				s += "/128"
+			} else {
+				s += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+		}
+		nets = append(nets, n)
+	}
+	isTrusted := func(ip net.IP) bool {
+		for _, n := range nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(request *http.Request) string {
+		host, _, err := net.SplitHostPort(request.RemoteAddr)
+		if err != nil {
+			host = request.RemoteAddr
+		}
+		client := net.ParseIP(host)
+		if client == nil {
+			return ""
+		}
+		if !isTrusted(client) {
+			return ipKey(client, mask)
+		}
+		hops := forwardedFor(request.Header)
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(hops[i])
+			if ip == nil {
+				// Дальше цепочке доверять нельзя
+				break
+			}
+			client = ip
+			if !isTrusted(ip) {
+				break
+			}
+		}
+		return ipKey(client, mask)
+	}, nil
+}
+
+// ipKey возвращает ключ для адреса, адреса IPv6 заменяются подсетью по маске `mask`
+func ipKey(ip net.IP, mask net.IPMask) string {
+	if ip.To4() != nil {
+		return "ip:" + ip.String()
+	}
+	ones, bits := mask.Size()
+	if ones == bits {
+		return "ip:" + ip.String()
+	}
+	return "ip:" + (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+}
+
+// forwardedFor возвращает адреса из всех заголовков X-Forwarded-For по порядку
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	for _, value := range h.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// ContextKey выбирает раздел по значению в контексте запроса, например, по пользователю
+// или ключу API, которые положил туда middleware аутентификации. Значение должно быть
+// проверенным: если брать ключ из заголовка как есть, клиент получит свежую квоту
+// на каждое новое значение и сможет вытеснить чужие лимитеры
+func ContextKey(key interface{}) KeyFunc {
+	return func(request *http.Request) string {
+		v := request.Context().Value(key)
+		if v == nil {
+			return ""
+		}
+		s := fmt.Sprint(v)
+		if s == "" {
+			return ""
+		}
+		return "user:" + s
+	}
+}
+
+// HeaderKey выбирает раздел по заголовку `name`, например, по ключу API. Раздел создается
+// только для значений, которые приняла `valid`: иначе клиент получит свежую квоту на каждое
+// новое значение и сможет вытеснить чужие лимитеры. Для остальных значений ключ пустой
+func HeaderKey(name string, valid func(value string) bool) KeyFunc {
+	if valid == nil {
+		panic(fmt.Sprintf("middleware: nil validator for header %s in HeaderKey", name))
+	}
+	return func(request *http.Request) string {
+		v := request.Header.Get(name)
+		if v == "" || !valid(v) {
+			return ""
+		}
+		return "header:" + v
+	}
+}
+
+// FirstKey возвращает первый непустой ключ из `funcs`. Ключи разных функций
+// имеют разные префиксы и не пересекаются
+func FirstKey(funcs ...KeyFunc) KeyFunc {
+	return func(request *http.Request) string {
+		for _, f := range funcs {
+			if key := f(request); key != "" {
+				return key
+			}
+		}
+		return ""
+	}
+}
+
+const (
+	defaultMaxKeys = 10000
+	defaultKeyTTL  = 10 * time.Minute
+)
+
+type PartitionOption func(*partitions)
+
+// WithMaxKeys ограничивает число хранимых лимитеров, при превышении
+// удаляется тот, что дольше всех не использовался. `n` должно быть положительным
+func WithMaxKeys(n int) PartitionOption {
+	return func(p *partitions) {
+		p.maxKeys = n
+	}
+}
+
+// WithKeyTTL задает, через сколько после последнего запроса лимитер удаляется.
+// Время должно быть не меньше периода лимитера, иначе квота будет сбрасываться раньше
+func WithKeyTTL(ttl time.Duration) PartitionOption {
+	return func(p *partitions) {
+		p.ttl = ttl
+	}
+}
+
+// partitions хранит лимитеры по ключам с вытеснением по LRU и TTL
+type partitions struct {
+	mutex   sync.Mutex
+	factory func(key string) RateLimiter
+	maxKeys int
+	ttl     time.Duration
+	// Элементы *partition, в начале - использованные последними
+	lru  list.List
+	keys map[string]*list.Element
+}
+
+type partition struct {
+	key      string
+	limiter  RateLimiter
+	lastUsed time.Time
+}
+
+func newPartitions(factory func(key string) RateLimiter, opts []PartitionOption) *partitions {
+	p := &partitions{factory: factory, maxKeys: defaultMaxKeys, ttl: defaultKeyTTL, keys: make(map[string]*list.Element)}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.maxKeys <= 0 {
+		panic(fmt.Sprintf("middleware: non-positive max keys %d", p.maxKeys))
+	}
+	return p
+}
+
+// get возвращает лимитер для ключа, создавая его при необходимости
+func (p *partitions) get(key string) RateLimiter {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	now := timeFunc()
+	p.evictExpired(now)
+	if elem, ok := p.keys[key]; ok {
+		part := elem.Value.(*partition)
+		part.lastUsed = now
+		p.lru.MoveToFront(elem)
+		return part.limiter
+	}
+
+	part := &partition{key: key, limiter: p.factory(key), lastUsed: now}
+	p.keys[key] = p.lru.PushFront(part)
+	for p.lru.Len() > p.maxKeys {
+		p.remove(p.lru.Back())
+	}
+	return part.limiter
+}
+
+func (p *partitions) evictExpired(now time.Time) {
+	if p.ttl <= 0 {
+		return
+	}
+	for back := p.lru.Back(); back != nil && now.Sub(back.Value.(*partition).lastUsed) >= p.ttl; back = p.lru.Back() {
+		p.remove(back)
+	}
+}
+
+func (p *partitions) remove(elem *list.Element) {
+	delete(p.keys, p.lru.Remove(elem).(*partition).key)
+}
+
+func (p *partitions) size() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.lru.Len()
+}
+
+// KeyedRateLimitMiddleware ограничивает частоту запросов отдельно для каждого ключа
+type KeyedRateLimitMiddleware struct {
+	key        KeyFunc
+	partitions *partitions
+	handler    http.Handler
+}
+
+func (krlHandler KeyedRateLimitMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	limiter := krlHandler.partitions.get(krlHandler.key(request))
+	RateLimitMiddleware{limiter: limiter, handler: krlHandler.handler}.ServeHTTP(writer, request)
+}
+
+// KeyedRateLimit создает middleware, в котором для каждого ключа из `key` создается
+// свой лимитер через `factory`. Запросы без ключа учитываются в общем разделе с пустым ключом
+func KeyedRateLimit(key KeyFunc, factory func(key string) RateLimiter, opts ...PartitionOption) func(http.Handler) http.Handler {
+	p := newPartitions(factory, opts)
+	return func(handler http.Handler) http.Handler {
+		return KeyedRateLimitMiddleware{key: key, partitions: p, handler: handler}
+	}
+}
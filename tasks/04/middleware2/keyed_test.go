@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIP(t *testing.T) {
+	key, err := ClientIP("10.0.0.0/8", "192.168.1.1", "::1")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		Name       string
+		RemoteAddr string
+		XFF        []string
+		Key        string
+	}{
+		{Name: "direct", RemoteAddr: "1.2.3.4:1234", Key: "ip:1.2.3.4"},
+		{Name: "untrusted proxy", RemoteAddr: "1.2.3.4:1234", XFF: []string{"5.6.7.8"}, Key: "ip:1.2.3.4"},
+		{Name: "trusted proxy", RemoteAddr: "10.1.1.1:1234", XFF: []string{"5.6.7.8"}, Key: "ip:5.6.7.8"},
+		{Name: "chain", RemoteAddr: "10.1.1.1:1234", XFF: []string{"6.6.6.6, 5.6.7.8, 192.168.1.1"}, Key: "ip:5.6.7.8"},
+		{Name: "several headers", RemoteAddr: "[::1]:1234", XFF: []string{"6.6.6.6, 5.6.7.8", "10.2.2.2"}, Key: "ip:5.6.7.8"},
+		{Name: "all trusted", RemoteAddr: "10.1.1.1:1234", XFF: []string{"10.3.3.3, 10.2.2.2"}, Key: "ip:10.3.3.3"},
+		{Name: "no header", RemoteAddr: "10.1.1.1:1234", Key: "ip:10.1.1.1"},
+		{Name: "garbage", RemoteAddr: "10.1.1.1:1234", XFF: []string{"5.6.7.8, garbage, 10.2.2.2"}, Key: "ip:10.2.2.2"},
+		{Name: "invalid remote", RemoteAddr: "pipe", Key: ""},
+	} {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.RemoteAddr
+			for _, v := range tc.XFF {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			require.Equal(t, tc.Key, key(req))
+		})
+	}
+
+	_, err = ClientIP("not an ip")
+	require.Error(t, err)
+}
+
+func TestClientIP_IPv6(t *testing.T) {
+	key, err := ClientIP("2001:db8:ffff::1")
+	require.NoError(t, err)
+	full, err := ClientIPPrefix(128)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		Name       string
+		RemoteAddr string
+		XFF        string
+		Key        string
+		Full       string
+	}{
+		{Name: "direct", RemoteAddr: "[2001:db8:1:2:3:4:5:6]:1234", Key: "ip:2001:db8:1:2::/64", Full: "ip:2001:db8:1:2:3:4:5:6"},
+		{Name: "same subnet", RemoteAddr: "[2001:db8:1:2:ffff::1]:1234", Key: "ip:2001:db8:1:2::/64", Full: "ip:2001:db8:1:2:ffff::1"},
+		{Name: "proxy", RemoteAddr: "[2001:db8:ffff::1]:1234", XFF: "2001:db8:5:6::7", Key: "ip:2001:db8:5:6::/64", Full: "ip:2001:db8:ffff::1"},
+		{Name: "ipv4", RemoteAddr: "1.2.3.4:1234", Key: "ip:1.2.3.4", Full: "ip:1.2.3.4"},
+		{Name: "ipv4-mapped", RemoteAddr: "[::ffff:1.2.3.4]:1234", Key: "ip:1.2.3.4", Full: "ip:1.2.3.4"},
+	} {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.RemoteAddr
+			if tc.XFF != "" {
+				req.Header.Set("X-Forwarded-For", tc.XFF)
+			}
+			require.Equal(t, tc.Key, key(req))
+			require.Equal(t, tc.Full, full(req))
+		})
+	}
+
+	key, err = ClientIPPrefix(48)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "[2001:db8:1:2:3:4:5:6]:1234"
+	require.Equal(t, "ip:2001:db8:1::/48", key(req))
+
+	_, err = ClientIPPrefix(129)
+	require.Error(t, err)
+	_, err = ClientIPPrefix(-1)
+	require.Error(t, err)
+}
+
+type userKey struct{}
+
+func TestFirstKey(t *testing.T) {
+	ip, err := ClientIP()
+	require.NoError(t, err)
+	valid := func(v string) bool { return v == "k1" }
+	key := FirstKey(HeaderKey("X-API-Key", valid), ContextKey(userKey{}), ip)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "1.2.3.4:1234"
+	require.Equal(t, "ip:1.2.3.4", key(req))
+
+	req = req.WithContext(context.WithValue(req.Context(), userKey{}, "alice"))
+	require.Equal(t, "user:alice", key(req))
+
+	// Непроверенный ключ API не используется
+	req.Header.Set("X-API-Key", "k2")
+	require.Equal(t, "user:alice", key(req))
+	req.Header.Set("X-API-Key", "k1")
+	require.Equal(t, "header:k1", key(req))
+
+	require.Panics(t, func() { HeaderKey("X-API-Key", nil) })
+}
+
+func TestPartitions(t *testing.T) {
+	clock := newFakeClock(t)
+	created := 0
+	p := newPartitions(func(key string) RateLimiter {
+		created++
+		return NewGCRA(1, time.Second)
+	}, []PartitionOption{WithMaxKeys(2), WithKeyTTL(time.Minute)})
+
+	a := p.get("a")
+	require.Same(t, a, p.get("a"))
+	p.get("b")
+	// "a" использован последним, поэтому вытесняется "b"
+	p.get("a")
+	p.get("c")
+	require.Equal(t, 2, p.size())
+	require.Same(t, a, p.get("a"))
+	require.Equal(t, 3, created)
+	p.get("b")
+	require.Equal(t, 4, created)
+
+	clock.Advance(30 * time.Second)
+	p.get("a")
+	clock.Advance(30 * time.Second)
+	// "b" не использовался минуту и удаляется по TTL при следующем обращении
+	p.get("d")
+	require.Equal(t, 2, p.size())
+	require.Contains(t, p.keys, "a")
+	require.Contains(t, p.keys, "d")
+
+	clock.Advance(time.Hour)
+	p.get("e")
+	require.Equal(t, 1, p.size())
+
+	factory := func(key string) RateLimiter { return NewGCRA(1, time.Second) }
+	require.Panics(t, func() { newPartitions(factory, []PartitionOption{WithMaxKeys(0)}) })
+	require.Panics(t, func() { newPartitions(factory, []PartitionOption{WithMaxKeys(-1)}) })
+}
+
+// authenticate кладет в контекст клиента, если ключ API из заголовка известен
+func authenticate(apiKeys map[string]string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if client, ok := apiKeys[req.Header.Get("X-API-Key")]; ok {
+			req = req.WithContext(context.WithValue(req.Context(), userKey{}, client))
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+func TestKeyedRateLimit(t *testing.T) {
+	newFakeClock(t)
+	ip, err := ClientIP()
+	require.NoError(t, err)
+	apiKeys := map[string]string{"noisy-key": "noisy"}
+	for i := 0; i < 10; i++ {
+		apiKeys["quiet-key"+strconv.Itoa(i)] = "quiet" + strconv.Itoa(i)
+	}
+	limit := KeyedRateLimit(FirstKey(ContextKey(userKey{}), ip), func(key string) RateLimiter {
+		return NewTokenBucket(2, time.Minute)
+	})
+	limited := limit(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	handler := authenticate(apiKeys, limited)
+	serve := func(apiKey string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "1.2.3.4:1234"
+		req.Header.Set("X-API-Key", apiKey)
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req)
+		return rw.Code
+	}
+
+	// Шумный клиент исчерпывает только свою квоту
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusOK, serve("noisy-key"), i)
+	}
+	require.Equal(t, http.StatusTooManyRequests, serve("noisy-key"))
+	for i := 0; i < 10; i++ {
+		require.Equal(t, http.StatusOK, serve("quiet-key"+strconv.Itoa(i)))
+	}
+	require.Equal(t, 11, limited.(KeyedRateLimitMiddleware).partitions.size())
+
+	// Неизвестные ключи не создают новых лимитеров и делят квоту адреса
+	require.Equal(t, http.StatusOK, serve("random1"))
+	require.Equal(t, http.StatusOK, serve("random2"))
+	for i := 0; i < 10; i++ {
+		require.Equal(t, http.StatusTooManyRequests, serve("random"+strconv.Itoa(i+3)))
+	}
+	require.Equal(t, 12, limited.(KeyedRateLimitMiddleware).partitions.size())
+}
+
+func TestKeyedRateLimit_HeaderKey(t *testing.T) {
+	newFakeClock(t)
+	ip, err := ClientIP()
+	require.NoError(t, err)
+	apiKeys := map[string]bool{"k1": true, "k2": true}
+	valid := func(v string) bool { return apiKeys[v] }
+	limited := KeyedRateLimit(FirstKey(HeaderKey("X-API-Key", valid), ip), func(key string) RateLimiter {
+		return NewTokenBucket(1, time.Minute)
+	})(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	serve := func(apiKey string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "1.2.3.4:1234"
+		req.Header.Set("X-API-Key", apiKey)
+		rw := httptest.NewRecorder()
+		limited.ServeHTTP(rw, req)
+		return rw.Code
+	}
+
+	require.Equal(t, http.StatusOK, serve("k1"))
+	require.Equal(t, http.StatusTooManyRequests, serve("k1"))
+	require.Equal(t, http.StatusOK, serve("k2"))
+
+	// Случайные ключи учитываются по адресу
+	require.Equal(t, http.StatusOK, serve("random1"))
+	for i := 0; i < 10; i++ {
+		require.Equal(t, http.StatusTooManyRequests, serve("random"+strconv.Itoa(i+2)))
+	}
+	require.Equal(t, 3, limited.(KeyedRateLimitMiddleware).partitions.size())
+}
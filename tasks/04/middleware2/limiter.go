@@ -1,31 +1,75 @@
 package middleware
 
 import (
+	"container/list"
+	"context"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type LimiterMiddleware struct {
 	limiter *Limiter
 	handler *http.Handler
+
+	// Очередь ожидания: не больше queueSize запросов ждут освобождения места до queueTimeout
+	queueSize    int64
+	queueTimeout time.Duration
+	waiting      *int64
 }
 
 func (limHandler LimiterMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
-	if (*(limHandler.limiter)).TryAcquire() == true {
-		(*(limHandler.handler)).ServeHTTP(writer, request)
-	} else {
-		writer.WriteHeader(http.StatusTooManyRequests)
+	limiter := *(limHandler.limiter)
+	if !limiter.TryAcquire() && !limHandler.wait(request.Context()) {
+		if request.Context().Err() == nil {
+			writer.WriteHeader(http.StatusTooManyRequests)
+		}
+		return
+	}
+	defer limiter.Release()
+	(*(limHandler.handler)).ServeHTTP(writer, request)
+}
+
+// wait ставит запрос в очередь, если в ней есть место, и ждет освобождения лимита
+func (limHandler LimiterMiddleware) wait(ctx context.Context) bool {
+	if limHandler.queueSize == 0 {
+		return false
+	}
+	defer atomic.AddInt64(limHandler.waiting, -1)
+	if atomic.AddInt64(limHandler.waiting, 1) > limHandler.queueSize {
+		return false
 	}
+	ctx, cancel := context.WithTimeout(ctx, limHandler.queueTimeout)
+	defer cancel()
+	return (*(limHandler.limiter)).Acquire(ctx) == nil
 }
 
-func Limit(l Limiter) func(http.Handler) http.Handler {
+type Option func(*LimiterMiddleware)
+
+// WithQueue включает очередь: при достижении лимита до `size` запросов ждут
+// освобождения места не дольше `timeout` и только потом получают отказ
+func WithQueue(size int, timeout time.Duration) Option {
+	return func(limHandler *LimiterMiddleware) {
+		limHandler.queueSize = int64(size)
+		limHandler.queueTimeout = timeout
+	}
+}
+
+func Limit(l Limiter, opts ...Option) func(http.Handler) http.Handler {
 	return func(handler http.Handler) http.Handler {
-		return LimiterMiddleware{handler: &handler, limiter: &l}
+		limHandler := LimiterMiddleware{handler: &handler, limiter: &l, waiting: new(int64)}
+		for _, opt := range opts {
+			opt(&limHandler)
+		}
+		return limHandler
 	}
 }
 
 type Limiter interface {
 	TryAcquire() bool
+	// Acquire ждет освобождения места до отмены `ctx`
+	Acquire(ctx context.Context) error
 	Release()
 }
 
@@ -33,6 +77,9 @@ type MutexLimiter struct {
 	mutex   *sync.Mutex
 	cap     int
 	current int
+	// Каналы ожидающих Acquire в порядке очереди. Освободившееся место
+	// передается первому из них закрытием канала
+	waiters list.List
 }
 
 func NewMutexLimiter(count int) *MutexLimiter {
@@ -42,16 +89,53 @@ func NewMutexLimiter(count int) *MutexLimiter {
 func (l *MutexLimiter) TryAcquire() bool {
 	defer l.mutex.Unlock()
 	l.mutex.Lock()
-	if l.current < l.cap {
+	// Не обгоняем тех, кто уже ждет в Acquire
+	if l.current < l.cap && l.waiters.Len() == 0 {
 		l.current++
 		return true
 	}
 	return false
 }
 
+func (l *MutexLimiter) Acquire(ctx context.Context) error {
+	l.mutex.Lock()
+	if l.current < l.cap && l.waiters.Len() == 0 {
+		l.current++
+		l.mutex.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(ready)
+	l.mutex.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		l.mutex.Lock()
+		select {
+		case <-ready:
+			// Место успели передать: возвращаем его следующему
+			l.releaseLocked()
+		default:
+			l.waiters.Remove(elem)
+		}
+		l.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
 func (l *MutexLimiter) Release() {
 	defer l.mutex.Unlock()
 	l.mutex.Lock()
+	l.releaseLocked()
+}
+
+func (l *MutexLimiter) releaseLocked() {
+	if front := l.waiters.Front(); front != nil {
+		close(l.waiters.Remove(front).(chan struct{}))
+		return
+	}
 	l.current--
 }
 
@@ -72,6 +156,15 @@ func (l *ChanLimiter) TryAcquire() bool {
 	}
 }
 
+func (l *ChanLimiter) Acquire(ctx context.Context) error {
+	select {
+	case l.chSem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (l *ChanLimiter) Release() {
 	<-l.chSem
 }
@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/stretchr/testify/require"
@@ -118,3 +121,139 @@ func TestChanLimiter_Race(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func testAcquire(t *testing.T, l Limiter) {
+	for i := 0; i < count; i++ {
+		require.NoError(t, l.Acquire(context.Background()))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, l.Acquire(ctx), context.DeadlineExceeded)
+
+	acquired := make(chan error)
+	go func() {
+		acquired <- l.Acquire(context.Background())
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired over limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+	l.Release()
+	require.NoError(t, <-acquired)
+	require.False(t, l.TryAcquire())
+
+	for i := 0; i < count; i++ {
+		l.Release()
+	}
+	require.True(t, l.TryAcquire())
+}
+
+func TestMutexLimiter_Acquire(t *testing.T) {
+	testAcquire(t, NewMutexLimiter(count))
+}
+
+func TestChanLimiter_Acquire(t *testing.T) {
+	testAcquire(t, NewChanLimiter(count))
+}
+
+func TestMutexLimiter_AcquireRace(t *testing.T) {
+	var wg sync.WaitGroup
+	var l = NewMutexLimiter(count)
+	var current int64
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i%3)*time.Microsecond)
+				if l.Acquire(ctx) == nil {
+					if atomic.AddInt64(&current, 1) > count {
+						t.Error("limit exceeded")
+					}
+					atomic.AddInt64(&current, -1)
+					l.Release()
+				}
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Отмененные ожидания не должны терять места
+	for i := 0; i < count; i++ {
+		require.True(t, l.TryAcquire())
+	}
+	require.False(t, l.TryAcquire())
+}
+
+func TestLimit_Release(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Limit(NewMutexLimiter(1)))
+	r.Get("/", func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	for i := 0; i < 3; i++ {
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+	}
+}
+
+func TestLimit_Queue(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	handler := Limit(NewChanLimiter(1), WithQueue(1, time.Second))(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		started <- struct{}{}
+		<-release
+		rw.WriteHeader(http.StatusOK)
+	}))
+	serve := func() <-chan int {
+		res := make(chan int, 1)
+		go func() {
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+			res <- rw.Code
+		}()
+		return res
+	}
+
+	first := serve()
+	<-started
+	queued := serve()
+	waiting := handler.(LimiterMiddleware).waiting
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(waiting) == 1
+	}, time.Second, time.Millisecond)
+	// Очередь заполнена: третий запрос получает отказ сразу
+	require.Equal(t, http.StatusTooManyRequests, <-serve())
+
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, <-first)
+	<-started
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, <-queued)
+}
+
+func TestLimit_QueueTimeout(t *testing.T) {
+	l := NewMutexLimiter(1)
+	require.True(t, l.TryAcquire())
+	handler := Limit(l, WithQueue(10, 20*time.Millisecond))(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	start := time.Now()
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusTooManyRequests, rw.Code)
+	require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		l.Release()
+	}()
+	rw = httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+}
@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateResult - решение лимитера частоты запросов и состояние квоты после него
+type RateResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset - через сколько квота восстановится полностью
+	Reset time.Duration
+	// RetryAfter - через сколько можно повторить отклоненный запрос
+	RetryAfter time.Duration
+}
+
+// RateLimiter ограничивает частоту запросов
+type RateLimiter interface {
+	Allow() RateResult
+}
+
+// TokenBucket - корзина на `limit` токенов, которая полностью наполняется за `period`.
+// Каждый запрос забирает один токен
+type TokenBucket struct {
+	mutex    sync.Mutex
+	limit    int
+	interval time.Duration
+	tokens   float64
+	updated  time.Time
+}
+
+func NewTokenBucket(limit int, period time.Duration) *TokenBucket {
+	checkRate("NewTokenBucket", limit, period)
+	return &TokenBucket{limit: limit, interval: period / time.Duration(limit), tokens: float64(limit), updated: timeFunc()}
+}
+
+func (b *TokenBucket) Allow() RateResult {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := timeFunc()
+	b.tokens = math.Min(float64(b.limit), b.tokens+float64(now.Sub(b.updated))/float64(b.interval))
+	b.updated = now
+
+	res := RateResult{Limit: b.limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = b.duration(1 - b.tokens)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = b.duration(float64(b.limit) - b.tokens)
+	return res
+}
+
+// duration возвращает время, за которое наполнится `tokens` токенов
+func (b *TokenBucket) duration(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens * float64(b.interval)))
+}
+
+// SlidingWindowLog пропускает не больше `limit` запросов за любой интервал длины `window`.
+// Хранит время каждого пропущенного запроса, поэтому точен, но требует O(limit) памяти
+type SlidingWindowLog struct {
+	mutex  sync.Mutex
+	limit  int
+	window time.Duration
+	// Время пропущенных запросов по возрастанию
+	log []time.Time
+}
+
+func NewSlidingWindowLog(limit int, window time.Duration) *SlidingWindowLog {
+	checkRate("NewSlidingWindowLog", limit, window)
+	return &SlidingWindowLog{limit: limit, window: window, log: make([]time.Time, 0, limit)}
+}
+
+func (w *SlidingWindowLog) Allow() RateResult {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	now := timeFunc()
+	expired := 0
+	for expired < len(w.log) && !now.Before(w.log[expired].Add(w.window)) {
+		expired++
+	}
+	// Сдвигаем вместо w.log[expired:], чтобы не выделять память заново
+	w.log = w.log[:copy(w.log, w.log[expired:])]
+
+	res := RateResult{Limit: w.limit}
+	if len(w.log) < w.limit {
+		w.log = append(w.log, now)
+		res.Allowed = true
+	} else {
+		res.RetryAfter = w.log[0].Add(w.window).Sub(now)
+	}
+	res.Remaining = w.limit - len(w.log)
+	if len(w.log) > 0 {
+		res.Reset = w.log[len(w.log)-1].Add(w.window).Sub(now)
+	}
+	return res
+}
+
+// SlidingWindowCounter приближает скользящее окно по счетчикам текущего и предыдущего
+// фиксированных окон: запросы предыдущего окна учитываются с весом, равным доле,
+// которую оно занимает в скользящем окне. Требует O(1) памяти
+type SlidingWindowCounter struct {
+	mutex    sync.Mutex
+	limit    int
+	window   time.Duration
+	start    time.Time
+	current  int
+	previous int
+}
+
+func NewSlidingWindowCounter(limit int, window time.Duration) *SlidingWindowCounter {
+	checkRate("NewSlidingWindowCounter", limit, window)
+	return &SlidingWindowCounter{limit: limit, window: window, start: timeFunc().Truncate(window)}
+}
+
+func (w *SlidingWindowCounter) Allow() RateResult {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	now := timeFunc()
+	if elapsed := now.Sub(w.start); elapsed >= w.window {
+		if elapsed < 2*w.window {
+			w.previous = w.current
+		} else {
+			w.previous = 0
+		}
+		w.current = 0
+		w.start = now.Truncate(w.window)
+	}
+
+	// Вклад предыдущего окна, округленный вверх: лимит целый, поэтому запрос
+	// помещается в него тогда же, когда и при точной оценке
+	elapsed := now.Sub(w.start)
+	weighted := int(mulDivCeil(uint64(w.previous), uint64(w.window-elapsed), uint64(w.window)))
+
+	res := RateResult{Limit: w.limit, Reset: w.start.Add(2 * w.window).Sub(now)}
+	if weighted+w.current < w.limit {
+		w.current++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = w.retryAfter(elapsed)
+	}
+	if free := w.limit - w.current - weighted; free > 0 {
+		res.Remaining = free
+	}
+	if w.current == 0 {
+		res.Reset = w.start.Add(w.window).Sub(now)
+	}
+	return res
+}
+
+// retryAfter возвращает время, через которое вес предыдущего окна уменьшится
+// настолько, что запрос поместится в лимит
+func (w *SlidingWindowCounter) retryAfter(elapsed time.Duration) time.Duration {
+	free := w.limit - w.current - 1
+	if free < 0 || w.previous == 0 {
+		// Текущее окно заполнено: ждем его конца, дальше оно станет предыдущим
+		return w.window - elapsed
+	}
+	// previous * (window - t) / window <= free, округляем t вверх
+	t := mulDivCeil(uint64(w.window), uint64(w.previous-free), uint64(w.previous))
+	return time.Duration(t) - elapsed
+}
+
+// mulDivCeil возвращает a * b / c с округлением вверх. Произведение считается в 128 битах,
+// поэтому не переполняется, если результат помещается в uint64
+func mulDivCeil(a, b, c uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	lo, carry := bits.Add64(lo, c-1, 0)
+	q, _ := bits.Div64(hi+carry, lo, c)
+	return q
+}
+
+// GCRA (generic cell rate algorithm) пропускает не больше `limit` запросов за `period`,
+// равномерно восстанавливая квоту. Хранит только теоретическое время прихода
+// следующего запроса (TAT)
+type GCRA struct {
+	mutex    sync.Mutex
+	limit    int
+	period   time.Duration
+	interval time.Duration
+	tat      time.Time
+}
+
+func NewGCRA(limit int, period time.Duration) *GCRA {
+	checkRate("NewGCRA", limit, period)
+	return &GCRA{limit: limit, period: period, interval: period / time.Duration(limit)}
+}
+
+func (g *GCRA) Allow() RateResult {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	now := timeFunc()
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	res := RateResult{Limit: g.limit}
+	next := tat.Add(g.interval)
+	if allowAt := next.Add(-g.period); now.Before(allowAt) {
+		res.RetryAfter = allowAt.Sub(now)
+		next = tat
+	} else {
+		g.tat = next
+		res.Allowed = true
+	}
+	res.Remaining = int(now.Sub(next.Add(-g.period)) / g.interval)
+	res.Reset = next.Sub(now)
+	return res
+}
+
+// checkRate паникует на параметрах, с которыми лимитер не может работать. Лимит
+// не может превышать число наносекунд в периоде, иначе интервал между запросами равен нулю
+func checkRate(name string, limit int, period time.Duration) {
+	if limit <= 0 {
+		panic(fmt.Sprintf("middleware: non-positive limit %d for %s", limit, name))
+	}
+	if period <= 0 {
+		panic(fmt.Sprintf("middleware: non-positive period %v for %s", period, name))
+	}
+	if period/time.Duration(limit) == 0 {
+		panic(fmt.Sprintf("middleware: limit %d is too large for period %v in %s", limit, period, name))
+	}
+}
+
+// RateLimitMiddleware отклоняет запросы сверх частоты, заданной лимитером, и сообщает
+// состояние квоты в заголовках RateLimit-* (draft-ietf-httpapi-ratelimit-headers)
+type RateLimitMiddleware struct {
+	limiter RateLimiter
+	handler http.Handler
+}
+
+func (rlHandler RateLimitMiddleware) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	res := rlHandler.limiter.Allow()
+	writeRateHeaders(writer.Header(), res)
+	if !res.Allowed {
+		writer.Header().Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+		writer.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	rlHandler.handler.ServeHTTP(writer, request)
+}
+
+func writeRateHeaders(h http.Header, res RateResult) {
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+}
+
+// seconds округляет длительность вверх до целых секунд, как принято в заголовках
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+func RateLimit(l RateLimiter) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return RateLimitMiddleware{limiter: l, handler: handler}
+	}
+}
+
+// To mock time in tests
+var timeFunc = time.Now
@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock подменяет timeFunc и двигает время только явно
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock(t *testing.T) *fakeClock {
+	c := &fakeClock{now: time.Unix(1000, 0)}
+	timeFunc = func() time.Time { return c.now }
+	t.Cleanup(func() { timeFunc = time.Now })
+	return c
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func allowN(l RateLimiter, n int) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		if l.Allow().Allowed {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := newFakeClock(t)
+	b := NewTokenBucket(10, time.Second)
+
+	res := b.Allow()
+	require.Equal(t, RateResult{Allowed: true, Limit: 10, Remaining: 9, Reset: 100 * time.Millisecond}, res)
+	require.Equal(t, 9, allowN(b, 20))
+
+	res = b.Allow()
+	require.False(t, res.Allowed)
+	require.Equal(t, 0, res.Remaining)
+	require.Equal(t, 100*time.Millisecond, res.RetryAfter)
+	require.Equal(t, time.Second, res.Reset)
+
+	clock.Advance(250 * time.Millisecond)
+	require.Equal(t, 2, allowN(b, 5))
+	clock.Advance(time.Hour)
+	require.Equal(t, 10, allowN(b, 20))
+}
+
+func TestSlidingWindowLog(t *testing.T) {
+	clock := newFakeClock(t)
+	w := NewSlidingWindowLog(3, time.Second)
+
+	require.Equal(t, 2, allowN(w, 2))
+	clock.Advance(600 * time.Millisecond)
+	res := w.Allow()
+	require.Equal(t, RateResult{Allowed: true, Limit: 3, Remaining: 0, Reset: time.Second}, res)
+
+	res = w.Allow()
+	require.False(t, res.Allowed)
+	require.Equal(t, 400*time.Millisecond, res.RetryAfter)
+
+	// Первые два запроса вышли из окна, третий еще нет
+	clock.Advance(400 * time.Millisecond)
+	require.Equal(t, 2, allowN(w, 5))
+	clock.Advance(600 * time.Millisecond)
+	require.Equal(t, 1, allowN(w, 5))
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	clock := newFakeClock(t)
+	w := NewSlidingWindowCounter(10, time.Second)
+
+	require.Equal(t, 10, allowN(w, 20))
+	res := w.Allow()
+	require.False(t, res.Allowed)
+	require.Equal(t, time.Second, res.RetryAfter)
+
+	// Прошла четверть следующего окна: предыдущее учитывается с весом 3/4
+	clock.Advance(1250 * time.Millisecond)
+	res = w.Allow()
+	require.True(t, res.Allowed)
+	require.Equal(t, 1, res.Remaining)
+	require.Equal(t, 1, allowN(w, 5))
+
+	res = w.Allow()
+	require.False(t, res.Allowed)
+	// 10 * (1 - t) + 2 + 1 <= 10 при t >= 0.3
+	require.Equal(t, 50*time.Millisecond, res.RetryAfter)
+	clock.Advance(res.RetryAfter)
+	require.True(t, w.Allow().Allowed)
+
+	// Через два окна старые запросы не учитываются
+	clock.Advance(2 * time.Second)
+	require.Equal(t, 10, allowN(w, 20))
+}
+
+func TestSlidingWindowCounter_LargeLimit(t *testing.T) {
+	clock := newFakeClock(t)
+	const limit = 1000000
+	w := NewSlidingWindowCounter(limit, 24*time.Hour)
+
+	// limit * window не помещается в int64
+	res := w.Allow()
+	require.True(t, res.Allowed)
+	require.Equal(t, limit-1, res.Remaining)
+	require.Equal(t, limit-1, allowN(w, limit))
+	res = w.Allow()
+	require.False(t, res.Allowed)
+	require.Equal(t, 24*time.Hour-1000*time.Second, res.RetryAfter)
+
+	// Четверть следующего окна: предыдущее учитывается с весом 3/4
+	clock.Advance(30*time.Hour - 1000*time.Second)
+	res = w.Allow()
+	require.True(t, res.Allowed)
+	require.Equal(t, limit/4-1, res.Remaining)
+	require.Equal(t, limit/4-1, allowN(w, limit))
+	res = w.Allow()
+	require.False(t, res.Allowed)
+	// limit * (1 - t) + limit / 4 + 1 <= limit при t >= 1/4 + 1/limit
+	require.Equal(t, 24*time.Hour/limit, res.RetryAfter)
+}
+
+func TestGCRA(t *testing.T) {
+	clock := newFakeClock(t)
+	g := NewGCRA(5, time.Second)
+
+	res := g.Allow()
+	require.Equal(t, RateResult{Allowed: true, Limit: 5, Remaining: 4, Reset: 200 * time.Millisecond}, res)
+	require.Equal(t, 4, allowN(g, 10))
+
+	res = g.Allow()
+	require.False(t, res.Allowed)
+	require.Equal(t, 0, res.Remaining)
+	require.Equal(t, 200*time.Millisecond, res.RetryAfter)
+	require.Equal(t, time.Second, res.Reset)
+
+	// Квота восстанавливается равномерно
+	clock.Advance(450 * time.Millisecond)
+	require.Equal(t, 2, allowN(g, 10))
+	clock.Advance(time.Minute)
+	require.Equal(t, 5, allowN(g, 10))
+}
+
+func TestRateLimiters_InvalidArgs(t *testing.T) {
+	constructors := map[string]func(int, time.Duration) RateLimiter{
+		"TokenBucket":          func(l int, p time.Duration) RateLimiter { return NewTokenBucket(l, p) },
+		"SlidingWindowLog":     func(l int, p time.Duration) RateLimiter { return NewSlidingWindowLog(l, p) },
+		"SlidingWindowCounter": func(l int, p time.Duration) RateLimiter { return NewSlidingWindowCounter(l, p) },
+		"GCRA":                 func(l int, p time.Duration) RateLimiter { return NewGCRA(l, p) },
+	}
+	for name, newLimiter := range constructors {
+		newLimiter := newLimiter
+		t.Run(name, func(t *testing.T) {
+			require.Panics(t, func() { newLimiter(0, time.Second) })
+			require.Panics(t, func() { newLimiter(-1, time.Second) })
+			require.Panics(t, func() { newLimiter(10, 0) })
+			require.Panics(t, func() { newLimiter(10, -time.Second) })
+			require.Panics(t, func() { newLimiter(11, 10*time.Nanosecond) })
+			require.NotPanics(t, func() { newLimiter(10, 10*time.Nanosecond) })
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	clock := newFakeClock(t)
+	handler := RateLimit(NewGCRA(2, 10*time.Second))(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rw
+	}
+
+	rw := serve()
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.Equal(t, "2", rw.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", rw.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "5", rw.Header().Get("RateLimit-Reset"))
+	require.Empty(t, rw.Header().Get("Retry-After"))
+
+	require.Equal(t, http.StatusOK, serve().Code)
+	clock.Advance(1500 * time.Millisecond)
+	rw = serve()
+	require.Equal(t, http.StatusTooManyRequests, rw.Code)
+	require.Equal(t, "0", rw.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "9", rw.Header().Get("RateLimit-Reset"))
+	require.Equal(t, "4", rw.Header().Get("Retry-After"))
+}